@@ -0,0 +1,60 @@
+package masq_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+	"github.com/m-mizutani/masq"
+)
+
+func TestExportedCensor(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Phone string
+		Memo  string
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		Phone: "090-0000-1234",
+		Memo:  "call 090-0000-5678",
+	}
+
+	t.Run("compose with custom redactor", func(t *testing.T) {
+		c := masq.NewMasq(
+			masq.WithCensor(masq.FieldNameCensor("Phone"), masq.RedactString(func(s string) string {
+				return "****-" + s[len(s)-4:]
+			})),
+		)
+		copied := gt.Cast[myRecord](t, c.Redact(record))
+		gt.V(t, copied.Phone).Equal("****-1234")
+		gt.V(t, copied.Memo).Equal("call 090-0000-5678")
+	})
+
+	t.Run("combine censors in custom censor", func(t *testing.T) {
+		isPhone := masq.RegexCensor(regexp.MustCompile(`\d{3}-\d{4}-\d{4}`))
+		isMemo := masq.FieldNameCensor("Memo")
+		c := masq.NewMasq(
+			masq.WithCensor(func(fieldName string, value any, tag string) bool {
+				return isMemo(fieldName, value, tag) && isPhone(fieldName, value, tag)
+			}, masq.RedactConst("(memo)")),
+		)
+		copied := gt.Cast[myRecord](t, c.Redact(record))
+		gt.V(t, copied.Phone).Equal("090-0000-1234")
+		gt.V(t, copied.Memo).Equal("(memo)")
+	})
+
+	t.Run("censors", func(t *testing.T) {
+		censors := masq.Censors{
+			masq.ContainCensor("blue"),
+			masq.FieldPrefixCensor("Secure"),
+			masq.TagCensor("secret"),
+			masq.TypeCensor[int](),
+		}
+		gt.B(t, censors.ShouldRedact("Name", "blue sky", "")).True()
+		gt.B(t, censors.ShouldRedact("SecureName", "orange", "")).True()
+		gt.B(t, censors.ShouldRedact("Name", "orange", "secret")).True()
+		gt.B(t, censors.ShouldRedact("Name", 5, "")).True()
+		gt.B(t, censors.ShouldRedact("Name", "orange", "")).False()
+	})
+}
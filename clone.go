@@ -1,25 +1,217 @@
 package masq
 
 import (
+	"bytes"
 	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 )
 
 type ctxKeyDepth struct{}
 
+// ctxKeyPath is a key of context to hold dotted path of the value from the root, e.g. "user.Credentials.Password". It's set only when an option requires the path.
+type ctxKeyPath struct{}
+
+// pathFromContext returns the dotted path of the value that is being redacted.
+func pathFromContext(ctx context.Context) string {
+	path, _ := ctx.Value(ctxKeyPath{}).(string)
+	return path
+}
+
+// withPath appends name to the path in ctx if the path is required by options. Elements of slice and array, and values of pointer and interface have the same path as their parent.
+func (x *Masq) withPath(ctx context.Context, name string) context.Context {
+	if !x.pathRequired && reportFromContext(ctx) == nil {
+		// the path is required only for the report of RedactWithReport in this call
+		return ctx
+	}
+
+	if parent := pathFromContext(ctx); parent != "" {
+		name = parent + "." + name
+	}
+	return context.WithValue(ctx, ctxKeyPath{}, name)
+}
+
+// ctxKeyReport is a key of context to hold *Report of RedactWithReport.
+type ctxKeyReport struct{}
+
+func reportFromContext(ctx context.Context) *Report {
+	report, _ := ctx.Value(ctxKeyReport{}).(*Report)
+	return report
+}
+
+// reportPath returns the path of the value for the report. fieldName is used if the path is not available.
+func reportPath(ctx context.Context, fieldName string) string {
+	if path := pathFromContext(ctx); path != "" {
+		return path
+	}
+	return fieldName
+}
+
+// mapKeyName returns a name of map key for path.
+func mapKeyName(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	if key.CanInterface() {
+		return fmt.Sprint(key.Interface())
+	}
+	return key.String()
+}
+
+// ctxKeyAllowed is a key of context to indicate that the value is in a field with allow tag. Filters are not applied to the value and its children.
+type ctxKeyAllowed struct{}
+
+// ctxKeyStrict is a key of context to hold *strictReport in strict mode.
+type ctxKeyStrict struct{}
+
+// strictReport records names of values that are matched by filters but not redacted in a redaction.
+type strictReport struct {
+	names []string
+}
+
+func strictReportFromContext(ctx context.Context) *strictReport {
+	report, _ := ctx.Value(ctxKeyStrict{}).(*strictReport)
+	return report
+}
+
+func (x *strictReport) add(ctx context.Context, fieldName string) {
+	name := pathFromContext(ctx)
+	if name == "" {
+		name = fieldName
+	}
+	x.names = append(x.names, name)
+}
+
+// err returns an error wrapping ErrNotRedacted if any value is recorded. It's safe to call with nil.
+func (x *strictReport) err() error {
+	if x == nil || len(x.names) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrNotRedacted, strings.Join(x.names, ", "))
+}
+
+// ctxKeyStructField is a key of context to hold the depth of the value that is a struct field. It's set only when an option requires it.
+type ctxKeyStructField struct{}
+
+// isStructField returns true if the value that is being redacted is a field of struct, not an element of slice or map in the field.
+func isStructField(ctx context.Context) bool {
+	fieldDepth, ok := ctx.Value(ctxKeyStructField{}).(int)
+	depth, _ := ctx.Value(ctxKeyDepth{}).(int)
+	return ok && fieldDepth == depth
+}
+
+// ctxKeyParent is a key of context to hold reflect.Value of the struct that has the value as a field. It's set only when an option requires it.
+type ctxKeyParent struct{}
+
+// parentFromContext returns the struct that has the value that is being redacted as a field. It returns false if the value is not a struct field or the struct can not be accessed, e.g. it's obtained via unexported field.
+func parentFromContext(ctx context.Context) (any, bool) {
+	if !isStructField(ctx) {
+		return nil, false
+	}
+	parent, ok := ctx.Value(ctxKeyParent{}).(reflect.Value)
+	if !ok || !parent.CanInterface() {
+		return nil, false
+	}
+	return parent.Interface(), true
+}
+
+// parentTypeFromContext returns the type of struct that has the value that is being redacted as a field.
+func parentTypeFromContext(ctx context.Context) (reflect.Type, bool) {
+	if !isStructField(ctx) {
+		return nil, false
+	}
+	parent, ok := ctx.Value(ctxKeyParent{}).(reflect.Value)
+	if !ok {
+		return nil, false
+	}
+	return parent.Type(), true
+}
+
+// ctxKeyVisiting is a key of context to mark map or slice that is being cloned in the ancestors of the current value. It's used to detect self-referential map and slice, e.g. map that contains itself via interface.
+type ctxKeyVisiting struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// visit marks src as being cloned in ctx. It returns false if src is already being cloned by an ancestor, that means src refers to itself.
+func visit(ctx context.Context, src reflect.Value) (context.Context, bool) {
+	if src.Len() == 0 {
+		return ctx, true
+	}
+
+	key := ctxKeyVisiting{ptr: src.Pointer(), len: src.Len(), typ: src.Type()}
+	if ctx.Value(key) != nil {
+		return ctx, false
+	}
+	return context.WithValue(ctx, key, true), true
+}
+
+// ctxKeyNodes is a key of context to count visited values in a redaction. The value is *int shared in the redaction.
+type ctxKeyNodes struct{}
+
+// ctxKeyLeafFilter is a key of context to keep the filter that matched a container with WithLeafOnly or WithTagCascade option. Leaves in the container are redacted by the filter.
+type ctxKeyLeafFilter struct{}
+
+// ctxKeyMapDepth is a key of context to count maps from the root to the current value. It's used only with WithMaxMapDepth option.
+type ctxKeyMapDepth struct{}
+
 const (
 	maxDepth = 32
 )
 
 var (
+	anyType           = reflect.TypeOf((*any)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	durationType      = reflect.TypeOf(time.Duration(0))
+	syncMapType       = reflect.TypeOf(sync.Map{})
+	mapStringAnyType  = reflect.TypeOf(map[string]any{})
+	sliceAnyType      = reflect.TypeOf([]any{})
+	reflectValueType  = reflect.TypeOf(reflect.Value{})
+	reflectTypeType   = reflect.TypeOf((*reflect.Type)(nil)).Elem()
+	redactableType    = reflect.TypeOf((*Redactable)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	// bigNumberTypes are types of math/big. They are copied by methods of math/big instead of reflection because their internal slices are unexported.
+	bigNumberTypes = map[reflect.Type]struct{}{
+		reflect.TypeOf(big.Int{}):   {},
+		reflect.TypeOf(big.Float{}): {},
+		reflect.TypeOf(big.Rat{}):   {},
+	}
+
 	// ignoreTypes is a map of types that should not be redacted. It lists types that can not be copied. For example, reflect.Type is a pointer to a struct and copying it causes panic. Especially, reflect.rtype is unexported type. Then, the ignoreTypes is list of string of type name.
 	ignoreTypes = map[string]struct{}{
 		"*reflect.rtype": {},
 	}
 )
 
-func (x *masq) clone(ctx context.Context, fieldName string, src reflect.Value, tag string) reflect.Value {
+func (x *Masq) clone(ctx context.Context, fieldName string, src reflect.Value, tag string) reflect.Value {
+	return x.cloneInto(ctx, fieldName, src, tag, reflect.Value{})
+}
+
+// cloneInto clones src in the same way as clone. If src is struct and into is valid, fields are copied into into instead of a new struct.
+func (x *Masq) cloneInto(ctx context.Context, fieldName string, src reflect.Value, tag string, into reflect.Value) reflect.Value {
+	// Interface value is unwrapped before filters. Otherwise, redactors receive the interface value and can not redact it as a value of the dynamic type, e.g. string in map[string]any.
+	if src.Kind() == reflect.Interface {
+		if src.IsNil() {
+			return src
+		}
+		src = src.Elem()
+	}
+
 	if v, ok := ctx.Value(ctxKeyDepth{}).(int); !ok {
 		ctx = context.WithValue(ctx, ctxKeyDepth{}, 0)
 	} else {
@@ -30,10 +222,39 @@ func (x *masq) clone(ctx context.Context, fieldName string, src reflect.Value, t
 
 	}
 
+	if nodes, ok := ctx.Value(ctxKeyNodes{}).(*int); ok {
+		if *nodes >= x.maxNodes {
+			return reflect.ValueOf(TruncatedMessage)
+		}
+		*nodes++
+	}
+
+	if x.jsonSafe {
+		if v, ok := jsonUnsafeValue(src); ok {
+			return v
+		}
+	}
+
 	if _, ok := x.allowedTypes[src.Type()]; ok {
-		return src
+		x.checkSkipped(ctx, fieldName, src, tag)
+		return x.formatDuration(src)
 	}
+	if len(x.allowedTypeNames) > 0 {
+		if t := src.Type(); t.Name() != "" {
+			if _, ok := x.allowedTypeNames[t.PkgPath()+"."+t.Name()]; ok {
+				x.checkSkipped(ctx, fieldName, src, tag)
+				return x.formatDuration(src)
+			}
+		}
+	}
+	if x.reflectDescribe {
+		if described, ok := x.describeReflect(ctx, fieldName, src, tag); ok {
+			return described
+		}
+	}
+
 	if _, ok := ignoreTypes[src.Type().String()]; ok {
+		x.checkSkipped(ctx, fieldName, src, tag)
 		return src
 	}
 
@@ -41,37 +262,126 @@ func (x *masq) clone(ctx context.Context, fieldName string, src reflect.Value, t
 		return reflect.New(src.Type()).Elem()
 	}
 
+	if _, ok := x.allowTags[tag]; ok && tag != "" {
+		ctx = context.WithValue(ctx, ctxKeyAllowed{}, true)
+	}
+	skipFilters, _ := ctx.Value(ctxKeyAllowed{}).(bool)
+	if x.nonZeroOnly && src.IsZero() {
+		skipFilters = true
+	}
+
+	canFilter := !skipFilters && !x.isAllowedValue(src) && !x.isRedactedValue(ctx, src)
+	if filter, ok := ctx.Value(ctxKeyLeafFilter{}).(*Filter); ok && canFilter && !x.isContainer(src.Type()) {
+		if !x.dryRun {
+			return x.redactMatched(ctx, fieldName, tag, filter, src)
+		}
+		x.recordMatch(ctx, fieldName, filter)
+		canFilter = false
+	}
+
 	for _, filter := range x.filters {
-		if filter.censor(fieldName, src.Interface(), tag) {
-			dst := reflect.New(src.Type())
+		if !canFilter {
+			break
+		}
 
-			if !filter.redactors.Redact(src, dst) {
-				_ = x.defaultRedactor(src, dst)
-			}
+		if !x.isEnabled(filter) {
+			continue
+		}
 
-			if !dst.CanInterface() {
-				return dst
+		if filter.censor(ctx, fieldName, src.Interface(), tag) {
+			if x.redactsLeaves(filter) && x.isContainer(src.Type()) {
+				// redact leaves in the container by the filter instead of the container itself
+				ctx = context.WithValue(ctx, ctxKeyLeafFilter{}, filter)
+				break
+			}
+			if !x.dryRun {
+				return x.redactMatched(ctx, fieldName, tag, filter, src)
 			}
-			return dst.Elem()
+			// keep the original value. Filters are not applied to fields and elements of it because they are not visited without dry-run
+			x.recordMatch(ctx, fieldName, filter)
+			ctx = context.WithValue(ctx, ctxKeyAllowed{}, true)
+			break
+		}
+	}
+
+	if !x.redactableDisabled && src.Type().Implements(redactableType) {
+		if v, ok := redactedValue(src); ok {
+			return v
+		}
+	}
+
+	if _, ok := x.stringifyTypes[src.Type()]; ok {
+		if v, ok := stringify(src); ok {
+			return v
+		}
+	}
+
+	if v, ok := x.cloneBigNumber(src); ok {
+		return v
+	}
+
+	if cloner, ok := x.typeCloners[src.Type()]; ok {
+		if v := cloner(src.Interface()); v != nil {
+			return reflect.ValueOf(v)
+		}
+		return reflect.Zero(src.Type())
+	}
+
+	if x.jsonPassthrough && src.CanInterface() && src.Type().Implements(jsonMarshalerType) {
+		// keep the original value to output it by MarshalJSON
+		return src
+	}
+
+	if !x.atomicsCopied && src.Type().PkgPath() == "sync/atomic" {
+		// types of sync/atomic must not be copied after first use
+		return reflect.Zero(src.Type())
+	}
+
+	if x.syncMapSupport && src.Type() == syncMapType && src.CanAddr() {
+		return x.cloneSyncMap(ctx, src)
+	}
+
+	if x.goStringRedaction && src.Kind() == reflect.String && canFilter {
+		if v, ok := x.redactGoString(ctx, src); ok {
+			return v
 		}
 	}
 
 	switch src.Kind() {
 	case reflect.String:
+		// string is immutable, then no need to copy it if not redacted. But a value obtained via unexported field can not be set to other value, so it should be copied.
+		if src.CanInterface() {
+			return src
+		}
 		dst := reflect.New(src.Type())
 		dst.Elem().SetString(src.String())
 		return dst.Elem()
 
 	case reflect.Struct:
-		dst := reflect.New(src.Type())
+		dst := into
+		if dst.IsValid() {
+			dst.SetZero()
+		} else {
+			dst = reflect.New(src.Type()).Elem()
+		}
 		t := src.Type()
+		replaced := map[int]reflect.Value{}
+		skipUnexported := x.withoutUnsafe || (x.protoSafe && isProtoMessage(t))
+
+		if x.parentRequired {
+			ctx = context.WithValue(ctx, ctxKeyParent{}, src)
+		}
 
 		for i := 0; i < t.NumField(); i++ {
 			f := t.Field(i)
 			srcValue := src.Field(i)
-			dstValue := dst.Elem().Field(i)
+			dstValue := dst.Field(i)
 
 			if !srcValue.CanInterface() {
+				if _, skip := x.skipFieldNames[f.Name]; skip || skipUnexported {
+					// unexported field can not be accessed without unsafe, it's internal state of protobuf message, or it's skipped by name. Then it's left as zero value
+					continue
+				}
 				dstValue = reflect.NewAt(dstValue.Type(), unsafe.Pointer(dstValue.UnsafeAddr())).Elem()
 
 				if !srcValue.CanAddr() {
@@ -95,53 +405,885 @@ func (x *masq) clone(ctx context.Context, fieldName string, src reflect.Value, t
 			}
 
 			tagValue := f.Tag.Get(x.tagKey)
-			copied := x.clone(ctx, f.Name, srcValue, tagValue)
+			var copied reflect.Value
+			if text, ok := x.tagDirective(f.Tag); ok && !x.dryRun {
+				copied = directiveValue(text, srcValue.Type())
+			} else {
+				fieldCtx := x.withPath(ctx, f.Name)
+				if x.structFieldRequired {
+					depth, _ := ctx.Value(ctxKeyDepth{}).(int)
+					fieldCtx = context.WithValue(fieldCtx, ctxKeyStructField{}, depth+1)
+				}
+				if x.tagOptionsEnabled {
+					var options []string
+					tagValue, options = splitTagOptions(tagValue)
+					fieldCtx = context.WithValue(fieldCtx, ctxKeyTagOptions{}, options)
+				}
+				copied = undrop(x.clone(fieldCtx, f.Name, srcValue, tagValue), dstValue.Type())
+			}
+			if !copied.Type().AssignableTo(dstValue.Type()) {
+				replaced[i] = copied
+				continue
+			}
 			dstValue.Set(copied)
 		}
-		return dst.Elem()
+
+		if len(replaced) > 0 {
+			return reshapeStruct(dst, replaced)
+		}
+		return dst
 
 	case reflect.Map:
+		if x.maxMapDepth > 0 {
+			depth, _ := ctx.Value(ctxKeyMapDepth{}).(int)
+			if depth >= x.maxMapDepth {
+				// nested map deeper than the limit is trusted and shared with the original value
+				return src
+			}
+			ctx = context.WithValue(ctx, ctxKeyMapDepth{}, depth+1)
+		}
+
+		if x.redactMapStructKeys && src.Type().Key().Kind() == reflect.Struct {
+			return x.cloneMapWithStructKeys(ctx, src)
+		}
+
+		ctx, ok := visit(ctx, src)
+		if !ok {
+			return reflect.ValueOf(CycleMessage)
+		}
+		if len(x.mapKeyCensors) > 0 && src.Type().Key().Kind() == reflect.String {
+			return x.cloneMapWithKeyRedaction(ctx, src)
+		}
+		if src.Type() == mapStringAnyType && src.CanInterface() {
+			return x.cloneMapStringAny(ctx, src.Interface().(map[string]any))
+		}
+
 		dst := reflect.MakeMap(src.Type())
 		keys := src.MapKeys()
+		if x.stableMapOrder {
+			sortMapKeys(keys)
+		}
 		for i := 0; i < src.Len(); i++ {
 			mValue := src.MapIndex(keys[i])
-			dst.SetMapIndex(keys[i], x.clone(ctx, keys[i].String(), mValue, ""))
+			copied := x.clone(x.withPath(ctx, mapKeyName(keys[i])), keys[i].String(), mValue, "")
+			if copied.Type() == droppedType {
+				continue
+			}
+			if !copied.Type().AssignableTo(dst.Type().Elem()) {
+				dst = reshapeMap(dst)
+			}
+			dst.SetMapIndex(keys[i], copied)
 		}
 		return dst
 
 	case reflect.Slice:
-		dst := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
-		for i := 0; i < src.Len(); i++ {
-			dst.Index(i).Set(x.clone(ctx, fieldName, src.Index(i), ""))
-		}
-		return dst
+		return x.cloneSlice(ctx, fieldName, src)
 
 	case reflect.Array:
 		if src.Len() == 0 {
 			return src // can not access to src.Index(0)
 		}
+		if x.uuidBytes && isUUIDBytes(src.Type()) {
+			return uuidString(src)
+		}
+		if x.byteArrayAsString && src.Type().Elem().Kind() == reflect.Uint8 {
+			if v, ok := byteArrayString(src); ok {
+				return v
+			}
+		}
 
 		dst := reflect.New(src.Type()).Elem()
 		for i := 0; i < src.Len(); i++ {
-			dst.Index(i).Set(x.clone(ctx, fieldName, src.Index(i), ""))
+			copied := undrop(x.clone(ctx, fieldName, src.Index(i), ""), dst.Type().Elem())
+			if !copied.Type().AssignableTo(dst.Type().Elem()) {
+				dst = reshapeList(dst, reflect.ArrayOf(src.Len(), anyType))
+			}
+			dst.Index(i).Set(copied)
 		}
 		return dst
 
 	case reflect.Ptr:
-		dst := reflect.New(src.Elem().Type())
-		copied := x.clone(ctx, fieldName, src.Elem(), tag)
+		copied := undrop(x.clone(ctx, fieldName, src.Elem(), tag), src.Elem().Type())
+		t := src.Elem().Type()
+		if !copied.Type().AssignableTo(t) {
+			t = copied.Type()
+		}
+		dst := reflect.New(t)
 		dst.Elem().Set(copied)
 		return dst
 
-	case reflect.Interface:
-		if src.IsNil() {
+	default:
+		if v, ok := x.durationToString(src); ok {
+			return v
+		}
+		if report := reportFromContext(ctx); report != nil && isUncloneable(src) {
+			report.Uncloneable = append(report.Uncloneable, reportPath(ctx, fieldName))
+		}
+		// scalar values are immutable, then no need to copy them in the same way as string.
+		if src.CanInterface() && isScalarKind(src.Kind()) {
 			return src
 		}
-		return x.clone(ctx, fieldName, src.Elem(), tag)
-
-	default:
 		dst := reflect.New(src.Type())
 		dst.Elem().Set(src)
 		return dst.Elem()
 	}
 }
+
+// ctxKeyTagOptions is a key of context to hold options of the tag value of the nearest struct field by WithTagOptions option, e.g. ["keep4"] of `masq:"secret,keep4"`.
+type ctxKeyTagOptions struct{}
+
+func tagOptionsFromContext(ctx context.Context) []string {
+	options, _ := ctx.Value(ctxKeyTagOptions{}).([]string)
+	return options
+}
+
+// splitTagOptions splits the tag value into the primary value and comma-separated options in the same way as json tag, e.g. "secret,keep4" into "secret" and ["keep4"].
+func splitTagOptions(tag string) (string, []string) {
+	primary, rest, found := strings.Cut(tag, ",")
+	if !found {
+		return primary, nil
+	}
+	return primary, strings.Split(rest, ",")
+}
+
+// tagDirectiveRedact is a prefix of tag value to redact the field with the following text by WithTagRedactDirective option.
+const tagDirectiveRedact = "redact="
+
+// tagDirective returns the replacement text if the struct tag has redact directive in the tag key set by WithTagRedactDirective option.
+func (x *Masq) tagDirective(tag reflect.StructTag) (string, bool) {
+	if !x.tagDirectiveEnabled {
+		return "", false
+	}
+
+	key := x.tagDirectiveKey
+	if key == "" {
+		key = x.tagKey
+	}
+	return strings.CutPrefix(tag.Get(key), tagDirectiveRedact)
+}
+
+// directiveValue returns text as a value of t if the kind of t is string. Otherwise, it returns text as string and the field type is changed.
+func directiveValue(text string, t reflect.Type) reflect.Value {
+	v := reflect.ValueOf(text)
+	if t.Kind() == reflect.String {
+		return v.Convert(t)
+	}
+	return v
+}
+
+// redactMatched redacts src matched by the filter.
+func (x *Masq) redactMatched(ctx context.Context, fieldName, tag string, filter *Filter, src reflect.Value) reflect.Value {
+	x.recordMatch(ctx, fieldName, filter)
+	redacted := x.applyFilter(ctx, filter, src, fieldName, tag)
+	if redacted.Type() == sliceHeadType {
+		redacted = x.cloneSliceHead(ctx, redacted.Interface().(sliceHead))
+	}
+	x.checkRedacted(ctx, fieldName, src, redacted)
+	return redacted
+}
+
+// recordMatch notifies the filter matched with the value to the metrics callback and the report.
+func (x *Masq) recordMatch(ctx context.Context, fieldName string, filter *Filter) {
+	if x.metrics != nil {
+		x.metrics(filter.name)
+	}
+	if report := reportFromContext(ctx); report != nil {
+		report.Redacted = append(report.Redacted, ReportEntry{Path: reportPath(ctx, fieldName), Rule: filter.name})
+	}
+}
+
+// redactsLeaves returns true if leaves in a container matched by filter should be redacted instead of the container itself, by WithLeafOnly or WithTagCascade option.
+func (x *Masq) redactsLeaves(filter *Filter) bool {
+	return x.leafOnly || (x.tagCascade && filter.tag)
+}
+
+// isContainer returns true if values of t have elements or fields that are visited by masq one by one, that are map, slice, array, struct with exported fields and pointer to them. It's used by WithLeafOnly option. Structs without exported fields such as time.Time, math/big types and types registered by WithTypeCloner are treated as leaf.
+func (x *Masq) isContainer(t reflect.Type) bool {
+	if _, ok := x.typeCloners[t]; ok {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	case reflect.Ptr:
+		return x.isContainer(t.Elem())
+	case reflect.Struct:
+		if _, ok := bigNumberTypes[t]; ok {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applyFilter returns the value redacted by redactors of filter, or by the default redactor if no redactor redacts src.
+func (x *Masq) applyFilter(ctx context.Context, filter *Filter, src reflect.Value, fieldName, tag string) reflect.Value {
+	call := newRedactCall(x, src.Type(), redactInfo{fieldName: fieldName, tag: tag, tagOptions: tagOptionsFromContext(ctx), path: pathFromContext(ctx)})
+	done := filter.redactors.redact(call, src)
+
+	if !done {
+		if v, ok := x.redactFuncChan(src); ok {
+			return v
+		}
+		if x.preserveUncloneable && isUncloneable(src) {
+			return src
+		}
+		x.redactDefault(call, src)
+	}
+	if call.replacement.IsValid() {
+		return call.replacement
+	}
+
+	dst := call.dst
+	if !dst.CanInterface() {
+		return dst
+	}
+	return dst.Elem()
+}
+
+// isScalarKind returns true if values of kind k are immutable scalar, that are bool and numbers.
+func isScalarKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// isUncloneable returns true if src is a value that can not be deep copied, that are func, chan and unsafe.Pointer.
+func isUncloneable(src reflect.Value) bool {
+	switch src.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return true
+	}
+	return false
+}
+
+// redactedValue returns the value returned by Redacted method of src that implements Redactable. The value is converted into the type of src, e.g. string into a named string type, to keep the type of the container. If Redacted returns nil, it returns zero value of src. If the value can not be converted, it returns false.
+func redactedValue(src reflect.Value) (reflect.Value, bool) {
+	v := src.Interface().(Redactable).Redacted()
+	if v == nil {
+		return reflect.Zero(src.Type()), true
+	}
+	return convertRedactValue(reflect.ValueOf(v), src.Type())
+}
+
+// describeReflect converts reflect.Type into its type name, and reflect.Value into "kind:value" string if src is one of them. The value held by reflect.Value is redacted before formatting. If the value can not be accessed, e.g. it's obtained from unexported field, only the kind is described.
+func (x *Masq) describeReflect(ctx context.Context, fieldName string, src reflect.Value, tag string) (reflect.Value, bool) {
+	if src.Type().Implements(reflectTypeType) {
+		return reflect.ValueOf(src.Interface().(reflect.Type).String()), true
+	}
+	if src.Type() != reflectValueType {
+		return reflect.Value{}, false
+	}
+
+	v := src.Interface().(reflect.Value)
+	switch {
+	case !v.IsValid():
+		return reflect.ValueOf("invalid"), true
+	case !v.CanInterface():
+		return reflect.ValueOf(v.Kind().String()), true
+	}
+
+	redacted := undrop(x.clone(ctx, fieldName, v, tag), v.Type())
+	return reflect.ValueOf(fmt.Sprintf("%s:%v", v.Kind(), redacted.Interface())), true
+}
+
+// protoMessageFields are unexported fields that structs generated by protoc-gen-go have for internal state.
+var protoMessageFields = []string{"state", "sizeCache", "unknownFields"}
+
+// isProtoMessage returns true if t is a struct generated for protobuf message. It's detected by ProtoReflect method of proto.Message interface, or the well-known unexported fields to not depend on the protobuf module.
+func isProtoMessage(t reflect.Type) bool {
+	if _, ok := reflect.PointerTo(t).MethodByName("ProtoReflect"); ok {
+		return true
+	}
+
+	for _, name := range protoMessageFields {
+		f, ok := t.FieldByName(name)
+		if !ok || f.IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+// checkRedacted records the value in strict mode if redacted is same as non-zero src.
+func (x *Masq) checkRedacted(ctx context.Context, fieldName string, src, redacted reflect.Value) {
+	report := strictReportFromContext(ctx)
+	if report == nil || src.IsZero() || !redacted.CanInterface() || redacted.Type() != src.Type() {
+		return
+	}
+
+	if reflect.DeepEqual(src.Interface(), redacted.Interface()) {
+		report.add(ctx, fieldName)
+	}
+}
+
+// checkSkipped records the value in strict mode if non-zero src is matched by a filter but returned without applying filters, e.g. because the type is allowed.
+func (x *Masq) checkSkipped(ctx context.Context, fieldName string, src reflect.Value, tag string) {
+	report := strictReportFromContext(ctx)
+	if report == nil || src.IsZero() || !src.CanInterface() {
+		return
+	}
+
+	for _, filter := range x.filters {
+		if filter.censor(ctx, fieldName, src.Interface(), tag) {
+			report.add(ctx, fieldName)
+			return
+		}
+	}
+}
+
+// isAllowedValue returns true if src is string (or interface of string) and the value is allowed by WithAllowedValue option.
+func (x *Masq) isAllowedValue(src reflect.Value) bool {
+	if len(x.allowedValues) == 0 {
+		return false
+	}
+
+	if src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	if src.Kind() != reflect.String {
+		return false
+	}
+
+	_, ok := x.allowedValues[src.String()]
+	return ok
+}
+
+// isRedactedValue returns true if src is string (or interface of string) and the value is the redact message of masq. It means that the value has been already redacted, then filters do not need to be applied again. It makes redaction of already redacted value cheap and stable.
+func (x *Masq) isRedactedValue(ctx context.Context, src reflect.Value) bool {
+	if src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	if src.Kind() != reflect.String {
+		return false
+	}
+
+	if v, ok := x.kindRedactValues[reflect.String]; ok && v.Kind() == reflect.String {
+		return src.String() == v.String()
+	}
+	if x.lengthHint && x.isLengthHinted(src.String()) {
+		return true
+	}
+	if x.pathInMessage && x.isPathAnnotated(ctx, src.String()) {
+		return true
+	}
+	return src.String() == x.redactMessage
+}
+
+// isPathAnnotated returns true if s is the redact message annotated with the path of the value that is being visited by WithPathInMessage, optionally followed by the length hint. A message with other notes is not treated as redacted because it may be crafted by input, e.g. "[REDACTED:pw=secret]".
+func (x *Masq) isPathAnnotated(ctx context.Context, s string) bool {
+	path := pathFromContext(ctx)
+	if path == "" {
+		return false
+	}
+	note, ok := x.noteOfMessage(s)
+	if !ok {
+		return false
+	}
+	rest, ok := strings.CutPrefix(note, path)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	hint, ok := strings.CutPrefix(rest, ":")
+	return ok && x.lengthHint && hint != "" && strings.Trim(hint, "0123456789") == ""
+}
+
+// jsonUnsafeValue returns a placeholder string if src can not be encoded by encoding/json. It returns false if src can be encoded or src is a container that should be checked by walking its elements.
+func jsonUnsafeValue(src reflect.Value) (reflect.Value, bool) {
+	switch src.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return reflect.ValueOf("<" + src.Kind().String() + ">"), true
+
+	case reflect.Float32, reflect.Float64:
+		if f := src.Float(); math.IsNaN(f) || math.IsInf(f, 0) {
+			return reflect.ValueOf(strconv.FormatFloat(f, 'g', -1, 64)), true
+		}
+
+	case reflect.Map:
+		// encoding/json supports only string, integer and encoding.TextMarshaler as map key
+		key := src.Type().Key()
+		switch key.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !key.Implements(textMarshalerType) {
+				return reflect.ValueOf("<" + src.Type().String() + ">"), true
+			}
+		}
+	}
+
+	if src.CanInterface() {
+		if m, ok := src.Interface().(json.Marshaler); ok && !(src.Kind() == reflect.Ptr && src.IsNil()) {
+			if _, err := m.MarshalJSON(); err != nil {
+				return reflect.ValueOf("<" + src.Type().String() + ">"), true
+			}
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+// cloneBigNumber copies src by methods of math/big if src is big.Int, big.Float, big.Rat or pointer to them. The copy has the same type as src, or it's string returned by String method if WithBigNumberString option is enabled.
+func (x *Masq) cloneBigNumber(src reflect.Value) (reflect.Value, bool) {
+	t := src.Type()
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+	if _, ok := bigNumberTypes[t]; !ok {
+		return reflect.Value{}, false
+	}
+
+	ptr := src
+	if !isPtr {
+		// methods of math/big types are defined with pointer receiver
+		ptr = reflect.New(t)
+		ptr.Elem().Set(src)
+	}
+
+	var copied fmt.Stringer
+	switch v := ptr.Interface().(type) {
+	case *big.Int:
+		copied = new(big.Int).Set(v)
+	case *big.Float:
+		copied = new(big.Float).Copy(v)
+	case *big.Rat:
+		copied = new(big.Rat).Set(v)
+	}
+
+	if x.bigNumberString {
+		return reflect.ValueOf(copied.String()), true
+	}
+	if isPtr {
+		return reflect.ValueOf(copied), true
+	}
+	return reflect.ValueOf(copied).Elem(), true
+}
+
+// stringify returns the output of String method of src as string. It returns false if src does not implement fmt.Stringer with value or pointer receiver, or src can not be accessed.
+func stringify(src reflect.Value) (reflect.Value, bool) {
+	if !src.CanInterface() {
+		return reflect.Value{}, false
+	}
+	if stringer, ok := src.Interface().(fmt.Stringer); ok {
+		return reflect.ValueOf(stringer.String()), true
+	}
+
+	ptr := reflect.New(src.Type())
+	ptr.Elem().Set(src)
+	if stringer, ok := ptr.Interface().(fmt.Stringer); ok {
+		return reflect.ValueOf(stringer.String()), true
+	}
+	return reflect.Value{}, false
+}
+
+// formatDuration returns src as string if src is time.Duration and WithDurationString option is enabled. Otherwise, it returns src as is.
+func (x *Masq) formatDuration(src reflect.Value) reflect.Value {
+	if v, ok := x.durationToString(src); ok {
+		return v
+	}
+	return src
+}
+
+func (x *Masq) durationToString(src reflect.Value) (reflect.Value, bool) {
+	if !x.durationString || src.Type() != durationType {
+		return reflect.Value{}, false
+	}
+	return reflect.ValueOf(time.Duration(src.Int()).String()), true
+}
+
+// isUUIDBytes returns true if t is 16 bytes array.
+func isUUIDBytes(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// uuidString returns 16 bytes array src as UUID string in the canonical form.
+func uuidString(src reflect.Value) reflect.Value {
+	var b [16]byte
+	for i := range b {
+		b[i] = byte(src.Index(i).Uint())
+	}
+	return reflect.ValueOf(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
+
+// goStringPairPattern matches "Key:value" pairs in a string formatted by %+v or %#v of fmt, e.g. "{ID:abc Password:secret}" and `main.T{ID:"abc", Password:"secret"}`. The value is a quoted string, or characters until a space, a comma or a bracket.
+var goStringPairPattern = regexp.MustCompile(`(?:^|[\s{,])([A-Za-z_][A-Za-z0-9_]*):("(?:[^"\\]|\\.)*"|[^\s{}\[\],]+)`)
+
+// redactGoString redacts values of "Key:value" pairs in src formatted by %+v or %#v of fmt with filters. The key is given to censors as the field name and the value is given as string. Quoted value is unquoted before calling censors, and quoted again after redaction. It returns false if no value is redacted.
+func (x *Masq) redactGoString(ctx context.Context, src reflect.Value) (reflect.Value, bool) {
+	s := src.String()
+	matches := goStringPairPattern.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return reflect.Value{}, false
+	}
+
+	var b strings.Builder
+	var last int
+	var redacted bool
+	for _, m := range matches {
+		key, value := s[m[2]:m[3]], s[m[4]:m[5]]
+		unquoted, err := strconv.Unquote(value)
+		quoted := err == nil
+		if !quoted {
+			unquoted = value
+		}
+
+		replaced, ok := x.redactGoStringValue(ctx, key, unquoted)
+		if !ok {
+			continue
+		}
+		if quoted {
+			replaced = strconv.Quote(replaced)
+		}
+		b.WriteString(s[last:m[4]])
+		b.WriteString(replaced)
+		last = m[5]
+		redacted = true
+	}
+	if !redacted {
+		return reflect.Value{}, false
+	}
+	b.WriteString(s[last:])
+	return reflect.ValueOf(b.String()).Convert(src.Type()), true
+}
+
+// redactGoStringValue redacts value of key in a string formatted by fmt with the first filter that matches it. It returns false if no filter matches, or the filter does not redact it as string.
+func (x *Masq) redactGoStringValue(ctx context.Context, key, value string) (string, bool) {
+	for _, filter := range x.filters {
+		if !x.isEnabled(filter) || !filter.censor(ctx, key, value, "") {
+			continue
+		}
+		x.recordMatch(ctx, key, filter)
+		if x.dryRun {
+			return "", false
+		}
+		redacted := x.applyFilter(ctx, filter, reflect.ValueOf(value), key, "")
+		if redacted.Kind() != reflect.String {
+			return x.redactMessage, true
+		}
+		return redacted.String(), true
+	}
+	return "", false
+}
+
+// byteArrayString returns the byte array as string value if the bytes without trailing zero bytes are printable UTF-8 text.
+func byteArrayString(src reflect.Value) (reflect.Value, bool) {
+	b := make([]byte, src.Len())
+	for i := range b {
+		b[i] = byte(src.Index(i).Uint())
+	}
+	b = bytes.TrimRight(b, "\x00")
+
+	if !utf8.Valid(b) {
+		return reflect.Value{}, false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return reflect.Value{}, false
+		}
+	}
+	return reflect.ValueOf(string(b)), true
+}
+
+// sortMapKeys sorts string and number keys of map in ascending order. Keys of other kinds are not sorted.
+func sortMapKeys(keys []reflect.Value) {
+	if len(keys) == 0 {
+		return
+	}
+
+	switch keys[0].Kind() {
+	case reflect.String:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Int() < keys[j].Int() })
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Uint() < keys[j].Uint() })
+	case reflect.Float32, reflect.Float64:
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Float() < keys[j].Float() })
+	}
+}
+
+// MapEntry is a pair of key and value of map. It is used as an element of redacted map instead of the original map when redacted keys collapse into the same key with WithRedactMapStructKeys or WithMapKeyValueRedaction option.
+type MapEntry struct {
+	Key   any
+	Value any
+}
+
+func (x *Masq) cloneMapWithStructKeys(ctx context.Context, src reflect.Value) reflect.Value {
+	dst := reflect.MakeMap(src.Type())
+	var entries []MapEntry
+
+	iter := src.MapRange()
+	for iter.Next() {
+		key := undrop(x.clone(ctx, "", iter.Key(), ""), iter.Key().Type())
+		value := x.clone(x.withPath(ctx, mapKeyName(iter.Key())), iter.Key().String(), iter.Value(), "")
+		if value.Type() == droppedType {
+			continue
+		}
+
+		if entries == nil {
+			if key.Type().AssignableTo(dst.Type().Key()) && !dst.MapIndex(key).IsValid() {
+				if !value.Type().AssignableTo(dst.Type().Elem()) {
+					dst = reshapeMap(dst)
+				}
+				dst.SetMapIndex(key, value)
+				continue
+			}
+
+			// redacted key can not be stored into the map without data loss. Then, switch to list of entries
+			entries = make([]MapEntry, 0, src.Len())
+			dstIter := dst.MapRange()
+			for dstIter.Next() {
+				entries = append(entries, MapEntry{Key: dstIter.Key().Interface(), Value: dstIter.Value().Interface()})
+			}
+		}
+
+		entries = append(entries, MapEntry{Key: key.Interface(), Value: value.Interface()})
+	}
+
+	if entries != nil {
+		return reflect.ValueOf(entries)
+	}
+	return dst
+}
+
+// mapKeyValueFilter is a filter to record matches of WithMapKeyValueRedaction in metrics and report.
+var mapKeyValueFilter = &Filter{name: "WithMapKeyValueRedaction"}
+
+// cloneMapWithKeyRedaction clones map that has string kind keys. If a key is matched by censors of WithMapKeyValueRedaction, both of the key and the value are replaced with the redact message. If redacted keys collapse into the same key, the map is converted into []MapEntry to prevent data loss. In dry-run, matched keys and values are kept as is.
+func (x *Masq) cloneMapWithKeyRedaction(ctx context.Context, src reflect.Value) reflect.Value {
+	keyType, elemType := src.Type().Key(), src.Type().Elem()
+	redactedKey := reflect.ValueOf(x.redactMessage).Convert(keyType)
+	redactedValue := reflect.ValueOf(x.redactMessage)
+	if elemType.Kind() == reflect.String {
+		redactedValue = redactedValue.Convert(elemType)
+	}
+
+	keys := src.MapKeys()
+	if x.stableMapOrder {
+		sortMapKeys(keys)
+	}
+
+	dst := reflect.MakeMapWithSize(src.Type(), len(keys))
+	var entries []MapEntry
+	for _, k := range keys {
+		key, value := k, redactedValue
+		entryCtx := x.withPath(ctx, k.String())
+		matched := x.mapKeyCensors.ShouldRedact(k.String(), k.String(), "")
+		if matched {
+			x.recordMatch(entryCtx, k.String(), mapKeyValueFilter)
+		}
+		if matched && !x.dryRun {
+			key = redactedKey
+		} else {
+			if matched {
+				// keep the original value in dry-run without applying filters to it
+				entryCtx = context.WithValue(entryCtx, ctxKeyAllowed{}, true)
+			}
+			value = x.clone(entryCtx, k.String(), src.MapIndex(k), "")
+			if value.Type() == droppedType {
+				continue
+			}
+		}
+
+		if entries == nil {
+			if !dst.MapIndex(key).IsValid() {
+				if !value.Type().AssignableTo(dst.Type().Elem()) {
+					dst = reshapeMap(dst)
+				}
+				dst.SetMapIndex(key, value)
+				continue
+			}
+
+			// redacted key can not be stored into the map without data loss. Then, switch to list of entries
+			entries = make([]MapEntry, 0, len(keys))
+			dstIter := dst.MapRange()
+			for dstIter.Next() {
+				entries = append(entries, MapEntry{Key: dstIter.Key().Interface(), Value: dstIter.Value().Interface()})
+			}
+		}
+
+		entries = append(entries, MapEntry{Key: key.Interface(), Value: value.Interface()})
+	}
+
+	if entries != nil {
+		return reflect.ValueOf(entries)
+	}
+	return dst
+}
+
+func (x *Masq) cloneSlice(ctx context.Context, fieldName string, src reflect.Value) reflect.Value {
+	ctx, ok := visit(ctx, src)
+	if !ok {
+		return reflect.ValueOf(CycleMessage)
+	}
+	if src.Type() == sliceAnyType && src.CanInterface() {
+		return x.cloneSliceAny(ctx, fieldName, src.Interface().([]any))
+	}
+
+	// capacity of the copied slice is same as the length to not keep memory beyond the length
+	dst := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+	for i := 0; i < src.Len(); i++ {
+		copied := undrop(x.clone(ctx, fieldName, src.Index(i), ""), dst.Type().Elem())
+		if !copied.Type().AssignableTo(dst.Type().Elem()) {
+			dst = reshapeList(dst, reflect.SliceOf(anyType))
+		}
+		dst.Index(i).Set(copied)
+	}
+	return dst
+}
+
+// cloneSliceHead clones kept elements of the slice by RedactSliceHead redactor. Elements are cloned without the field name of the slice because the filter of the field name has been already applied to the slice. If elements are omitted, the slice is converted to []any and a marker of the omitted count is appended.
+func (x *Masq) cloneSliceHead(ctx context.Context, head sliceHead) reflect.Value {
+	dst := x.cloneSlice(ctx, "", head.elems)
+	if head.omitted == 0 {
+		return dst
+	}
+
+	dst = reshapeList(dst, reflect.SliceOf(anyType))
+	return reflect.Append(dst, reflect.ValueOf(fmt.Sprintf("<%d more>", head.omitted)))
+}
+
+// cloneMapStringAny clones map[string]any, the common shape of structured log, without reflection for the map itself. Only MapKeys and SetMapIndex of the map are avoided, and each value is still cloned by clone with the key as field name in the same way as other maps.
+func (x *Masq) cloneMapStringAny(ctx context.Context, src map[string]any) reflect.Value {
+	keys := make([]string, 0, len(src))
+	for k := range src {
+		keys = append(keys, k)
+	}
+	if x.stableMapOrder {
+		sort.Strings(keys)
+	}
+
+	dst := make(map[string]any, len(src))
+	for _, k := range keys {
+		v := src[k]
+		if v == nil {
+			dst[k] = nil
+			continue
+		}
+
+		copied := x.clone(x.withPath(ctx, k), k, reflect.ValueOf(v), "")
+		if copied.Type() == droppedType {
+			continue
+		}
+		dst[k] = copied.Interface()
+	}
+	return reflect.ValueOf(dst)
+}
+
+// cloneSliceAny clones []any without reflection for the slice itself in the same way as cloneMapStringAny.
+func (x *Masq) cloneSliceAny(ctx context.Context, fieldName string, src []any) reflect.Value {
+	dst := make([]any, len(src))
+	for i, v := range src {
+		if v == nil {
+			continue
+		}
+
+		copied := x.clone(ctx, fieldName, reflect.ValueOf(v), "")
+		if copied.Type() == droppedType {
+			continue
+		}
+		dst[i] = copied.Interface()
+	}
+	return reflect.ValueOf(dst)
+}
+
+// cloneSyncMap returns a new sync.Map that has redacted values of src. src must be addressable sync.Map. Keys are stored as is.
+func (x *Masq) cloneSyncMap(ctx context.Context, src reflect.Value) reflect.Value {
+	dst := reflect.New(syncMapType)
+	m := dst.Interface().(*sync.Map)
+
+	src.Addr().Interface().(*sync.Map).Range(func(key, value any) bool {
+		if value == nil {
+			m.Store(key, nil)
+			return true
+		}
+
+		name := mapKeyName(reflect.ValueOf(key))
+		copied := x.clone(x.withPath(ctx, name), name, reflect.ValueOf(value), "")
+		if copied.Type() != droppedType {
+			m.Store(key, copied.Interface())
+		}
+		return true
+	})
+
+	return dst.Elem()
+}
+
+// reshapeStruct builds a new struct value that has the same exported fields as src except fields in replaced. A type of replaced field is changed to the type of the replaced value. It is used when a redacted value can not be stored into the original field type, e.g. a func field is described as string. Unexported fields are dropped and embedded fields are kept as normal named fields because reflect.StructOf does not support them.
+func reshapeStruct(src reflect.Value, replaced map[int]reflect.Value) reflect.Value {
+	t := src.Type()
+
+	var fields []reflect.StructField
+	var values []reflect.Value
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		v := src.Field(i)
+		if r, ok := replaced[i]; ok {
+			v = r
+		}
+
+		fields = append(fields, reflect.StructField{
+			Name: f.Name,
+			Type: v.Type(),
+			Tag:  f.Tag,
+		})
+		values = append(values, v)
+	}
+
+	dst := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, v := range values {
+		dst.Field(i).Set(v)
+	}
+	return dst
+}
+
+// reshapeMap converts map[K]V to map[K]any to store a value that has different type from V.
+func reshapeMap(src reflect.Value) reflect.Value {
+	if src.Type().Elem() == anyType {
+		return src
+	}
+
+	dst := reflect.MakeMapWithSize(reflect.MapOf(src.Type().Key(), anyType), src.Len())
+	iter := src.MapRange()
+	for iter.Next() {
+		dst.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return dst
+}
+
+// reshapeList converts slice or array to t, that is []any or [N]any, to store a value that has different type from the original element type.
+func reshapeList(src reflect.Value, t reflect.Type) reflect.Value {
+	if src.Type() == t {
+		return src
+	}
+
+	var dst reflect.Value
+	if t.Kind() == reflect.Slice {
+		dst = reflect.MakeSlice(t, src.Len(), src.Len())
+	} else {
+		dst = reflect.New(t).Elem()
+	}
+	for i := 0; i < src.Len(); i++ {
+		dst.Index(i).Set(src.Index(i))
+	}
+	return dst
+}
@@ -3,9 +3,15 @@ package masq_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sync"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/m-mizutani/gt"
 	"github.com/m-mizutani/masq"
@@ -249,6 +255,81 @@ func TestMapData(t *testing.T) {
 
 }
 
+func TestMapInterfaceData(t *testing.T) {
+	type user struct {
+		Name     string
+		Password string `masq:"secret"`
+	}
+
+	t.Run("tag of struct in map[string]any", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithTag("secret"))
+		copied := gt.Cast[map[string]any](t, c.Redact(map[string]any{
+			"user": user{Name: "m-mizutani", Password: "x"},
+		}))
+		gt.V(t, copied["user"]).Equal(user{Name: "m-mizutani", Password: masq.DefaultRedactMessage})
+	})
+
+	t.Run("pointer to struct in map[string]any", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithTag("secret"))
+		copied := gt.Cast[map[string]any](t, c.Redact(map[string]any{
+			"user": &user{Name: "m-mizutani", Password: "x"},
+		}))
+		gt.V(t, gt.Cast[*user](t, copied["user"]).Password).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("string value in map[string]any", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("password"))
+		copied := gt.Cast[map[string]any](t, c.Redact(map[string]any{
+			"password": "x",
+			"name":     "m-mizutani",
+		}))
+		gt.V(t, copied["password"]).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied["name"]).Equal("m-mizutani")
+	})
+
+	t.Run("deeply nested struct in map[string]any", func(t *testing.T) {
+		type database struct {
+			Host     string
+			Password string
+		}
+		type config struct {
+			Secret   string `masq:"secret"`
+			Database *database
+		}
+		data := map[string]any{
+			"cfg": config{Secret: "x", Database: &database{Host: "localhost", Password: "y"}},
+			"nested": map[string]any{
+				"configs": []any{config{Secret: "z"}},
+			},
+		}
+
+		c := masq.NewMasq(masq.WithTag("secret"), masq.WithFieldName("Password"))
+		copied := gt.Cast[map[string]any](t, c.Redact(data))
+
+		cfg := gt.Cast[config](t, copied["cfg"])
+		gt.V(t, cfg.Secret).Equal(masq.DefaultRedactMessage)
+		gt.V(t, cfg.Database.Host).Equal("localhost")
+		gt.V(t, cfg.Database.Password).Equal(masq.DefaultRedactMessage)
+
+		nested := gt.Cast[map[string]any](t, copied["nested"])
+		configs := gt.Cast[[]any](t, nested["configs"])
+		gt.V(t, gt.Cast[config](t, configs[0]).Secret).Equal(masq.DefaultRedactMessage)
+
+		gt.V(t, data["cfg"].(config).Database.Password).Equal("y")
+	})
+
+	t.Run("redactor receives dynamic value", func(t *testing.T) {
+		var kinds []reflect.Kind
+		c := masq.NewMasq(masq.WithFieldName("password", func(src, dst reflect.Value) bool {
+			kinds = append(kinds, src.Kind())
+			return false
+		}))
+		kinds = nil
+		_ = c.Redact(map[string]any{"password": "x"})
+		gt.V(t, kinds).Equal([]reflect.Kind{reflect.String})
+	})
+}
+
 func TestCloneUnexportedPointer(t *testing.T) {
 	c := masq.NewMasq(masq.WithContain("blue"))
 	type child struct {
@@ -266,6 +347,28 @@ func TestCloneUnexportedPointer(t *testing.T) {
 	gt.V(t, copied.c.Name).Equal("orange")
 }
 
+func TestSliceCapacity(t *testing.T) {
+	type myRecord struct {
+		Tags []string
+	}
+	tags := make([]string, 2, 16)
+	tags[0], tags[1] = "blue", "five"
+
+	c := masq.NewMasq(masq.WithContain("blue"))
+
+	t.Run("slice", func(t *testing.T) {
+		copied := gt.Cast[[]string](t, c.Redact(tags))
+		gt.V(t, copied).Equal([]string{masq.DefaultRedactMessage, "five"})
+		gt.V(t, cap(copied)).Equal(len(copied))
+	})
+
+	t.Run("slice in struct", func(t *testing.T) {
+		copied := gt.Cast[myRecord](t, c.Redact(myRecord{Tags: tags[:1]}))
+		gt.V(t, copied.Tags).Equal([]string{masq.DefaultRedactMessage})
+		gt.V(t, cap(copied.Tags)).Equal(1)
+	})
+}
+
 func TestDoublePointer(t *testing.T) {
 	c := masq.NewMasq(masq.WithContain("blue"))
 	type child struct {
@@ -344,6 +447,48 @@ func TestCircularReference(t *testing.T) {
 	gt.V(t, newData.Child.Child.Str).Equal("[REDACTED]")
 }
 
+func TestSelfReferentialMapAndSlice(t *testing.T) {
+	c := masq.NewMasq(masq.WithContain("blue"))
+
+	t.Run("map contains itself", func(t *testing.T) {
+		m := map[string]any{"color": "blue", "number": "five"}
+		m["self"] = m
+
+		copied := gt.Cast[map[string]any](t, c.Redact(m))
+		gt.V(t, copied).Equal(map[string]any{
+			"color":  masq.DefaultRedactMessage,
+			"number": "five",
+			"self":   masq.CycleMessage,
+		})
+		gt.V(t, m["color"]).Equal("blue")
+	})
+
+	t.Run("slice contains itself", func(t *testing.T) {
+		s := []any{"blue", nil}
+		s[1] = s
+
+		copied := gt.Cast[[]any](t, c.Redact(s))
+		gt.V(t, copied).Equal([]any{masq.DefaultRedactMessage, masq.CycleMessage})
+	})
+
+	t.Run("indirect cycle", func(t *testing.T) {
+		m := map[string]any{}
+		s := []any{m, "blue"}
+		m["list"] = s
+
+		copied := gt.Cast[map[string]any](t, c.Redact(m))
+		list := gt.Cast[[]any](t, copied["list"])
+		gt.V(t, list).Equal([]any{masq.CycleMessage, masq.DefaultRedactMessage})
+	})
+
+	t.Run("same map in siblings is not cycle", func(t *testing.T) {
+		shared := map[string]any{"color": "blue"}
+		copied := gt.Cast[map[string]any](t, c.Redact(map[string]any{"a": shared, "b": shared}))
+		gt.V(t, copied["a"]).Equal(any(map[string]any{"color": masq.DefaultRedactMessage}))
+		gt.V(t, copied["b"]).Equal(any(map[string]any{"color": masq.DefaultRedactMessage}))
+	})
+}
+
 func TestCloneFunc(t *testing.T) {
 	type myFunc func() string
 	src := myFunc(func() string { return "blue" })
@@ -361,3 +506,861 @@ func TestUnmarshalTypeError(t *testing.T) {
 	logger.Info("error", slog.Any("err", err))
 	gt.S(t, buf.String()).Contains("error")
 }
+
+func TestFuncChanMode(t *testing.T) {
+	type myStruct struct {
+		Func func() time.Time
+		Chan chan int
+		Name string
+	}
+	data := &myStruct{
+		Func: time.Now,
+		Chan: make(chan int),
+		Name: "blue",
+	}
+	censor := func(fieldName string, value any, tag string) bool {
+		return fieldName == "Func" || fieldName == "Chan"
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithCensor(censor), masq.WithFuncChanMode(masq.FuncChanZero))
+		copied := gt.Cast[*myStruct](t, c.Redact(data))
+		gt.V(t, copied.Func).Nil()
+		gt.V(t, copied.Chan).Nil()
+		gt.V(t, copied.Name).Equal("blue")
+	})
+
+	t.Run("preserve", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithCensor(censor), masq.WithFuncChanMode(masq.FuncChanPreserve))
+		copied := gt.Cast[*myStruct](t, c.Redact(data))
+		gt.V(t, copied.Func).NotNil()
+		gt.V(t, copied.Chan).Equal(data.Chan)
+		gt.V(t, copied.Name).Equal("blue")
+	})
+
+	t.Run("describe", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.New(masq.WithCensor(censor), masq.WithFuncChanMode(masq.FuncChanDescribe)),
+		}))
+		logger.Info("hello", slog.Any("data", data))
+		gt.S(t, buf.String()).
+			Contains(`"Func":"<func>"`).
+			Contains(`"Chan":"<chan>"`).
+			Contains(`"Name":"blue"`)
+	})
+
+	t.Run("describe in map", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithCensor(censor), masq.WithFuncChanMode(masq.FuncChanDescribe))
+		copied := gt.Cast[map[string]any](t, c.Redact(map[string]any{
+			"Func": data.Func,
+			"Name": "blue",
+		}))
+		gt.V(t, copied["Func"]).Equal("<func>")
+		gt.V(t, copied["Name"]).Equal("blue")
+	})
+
+	t.Run("describe in slice", func(t *testing.T) {
+		chanCensor := func(fieldName string, value any, tag string) bool {
+			return reflect.ValueOf(value).Kind() == reflect.Chan
+		}
+		c := masq.NewMasq(masq.WithCensor(chanCensor), masq.WithFuncChanMode(masq.FuncChanDescribe))
+		copied := gt.Cast[struct{ Chans []any }](t, c.Redact(struct{ Chans []chan int }{
+			Chans: []chan int{data.Chan},
+		}))
+		gt.V(t, copied.Chans).Equal([]any{"<chan>"})
+	})
+}
+
+func TestPreserveUncloneable(t *testing.T) {
+	type myRecord struct {
+		Callback func() string
+		Events   chan int
+		Ptr      unsafe.Pointer
+		Handler  any
+		Secret   string
+	}
+	n := 5
+	record := myRecord{
+		Callback: func() string { return "called" },
+		Events:   make(chan int),
+		Ptr:      unsafe.Pointer(&n),
+		Handler:  func() {},
+		Secret:   "blue",
+	}
+	options := []masq.Option{
+		masq.WithFieldName("Callback"),
+		masq.WithFieldName("Events"),
+		masq.WithFieldName("Ptr"),
+		masq.WithFieldName("Handler"),
+		masq.WithFieldName("Secret"),
+	}
+
+	t.Run("zero by default", func(t *testing.T) {
+		copied := gt.Cast[myRecord](t, masq.NewMasq(options...).Redact(record))
+		gt.B(t, copied.Callback == nil).True()
+		gt.B(t, copied.Events == nil).True()
+		gt.B(t, copied.Ptr == nil).True()
+		gt.V(t, copied.Handler).Nil()
+		gt.V(t, copied.Secret).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("preserve original value", func(t *testing.T) {
+		c := masq.NewMasq(append(options, masq.WithPreserveUncloneable())...)
+		copied := gt.Cast[myRecord](t, c.Redact(record))
+		gt.V(t, copied.Callback()).Equal("called")
+		gt.B(t, copied.Events == record.Events).True()
+		gt.B(t, copied.Ptr == record.Ptr).True()
+		gt.V(t, copied.Handler).NotNil()
+		gt.V(t, copied.Secret).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("FuncChanMode takes precedence", func(t *testing.T) {
+		c := masq.NewMasq(append(options,
+			masq.WithPreserveUncloneable(),
+			masq.WithFuncChanMode(masq.FuncChanDescribe),
+		)...)
+		v := reflect.ValueOf(c.Redact(record))
+		gt.V(t, v.FieldByName("Callback").Interface()).Equal(any("<func>"))
+		gt.B(t, v.FieldByName("Ptr").Interface().(unsafe.Pointer) == record.Ptr).True()
+	})
+}
+
+func TestRedactMapStructKeys(t *testing.T) {
+	type Key struct {
+		ID     string
+		Secret string `masq:"secret"`
+	}
+
+	t.Run("keys are not redacted by default", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithTag("secret"))
+		data := map[Key]string{
+			{ID: "a", Secret: "blue"}: "one",
+		}
+		copied := gt.Cast[map[Key]string](t, c.Redact(data))
+		gt.M(t, copied).HaveKeyValue(Key{ID: "a", Secret: "blue"}, "one")
+	})
+
+	t.Run("sensitive field in key is redacted", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithTag("secret"), masq.WithRedactMapStructKeys())
+		data := map[Key]string{
+			{ID: "a", Secret: "blue"}:   "one",
+			{ID: "b", Secret: "orange"}: "two",
+		}
+		copied := gt.Cast[map[Key]string](t, c.Redact(data))
+		gt.M(t, copied).
+			Length(2).
+			HaveKeyValue(Key{ID: "a", Secret: masq.DefaultRedactMessage}, "one").
+			HaveKeyValue(Key{ID: "b", Secret: masq.DefaultRedactMessage}, "two")
+
+		// original data is not modified
+		gt.M(t, data).HaveKey(Key{ID: "a", Secret: "blue"})
+	})
+
+	t.Run("collapsed keys are kept as entries", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithTag("secret"), masq.WithRedactMapStructKeys())
+		data := map[Key]string{
+			{ID: "a", Secret: "blue"}:   "one",
+			{ID: "a", Secret: "orange"}: "two",
+		}
+		entries := gt.Cast[[]masq.MapEntry](t, c.Redact(data))
+		gt.A(t, entries).Length(2).
+			All(func(v masq.MapEntry) bool {
+				return v.Key == Key{ID: "a", Secret: masq.DefaultRedactMessage}
+			}).
+			Any(func(v masq.MapEntry) bool { return v.Value == "one" }).
+			Any(func(v masq.MapEntry) bool { return v.Value == "two" })
+	})
+}
+
+func BenchmarkStringClone(b *testing.B) {
+	type myStruct struct {
+		Name  string
+		Label string
+		Tags  []string
+	}
+	data := &myStruct{
+		Name:  "orange",
+		Label: "five",
+		Tags:  []string{"a", "b", "c", "d"},
+	}
+	c := masq.NewMasq(masq.WithContain("blue"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.Redact(data)
+	}
+}
+
+func TestScalarClone(t *testing.T) {
+	type level int
+	type myStruct struct {
+		ID      int
+		Score   float64
+		Enabled bool
+		Level   level
+		Ratio   complex128
+		Values  []uint8
+		Any     any
+		count   int64
+		flag    bool
+	}
+	data := &myStruct{
+		ID:      1,
+		Score:   3.14,
+		Enabled: true,
+		Level:   level(3),
+		Ratio:   complex(1, 2),
+		Values:  []uint8{1, 2, 3},
+		Any:     int32(7),
+		count:   5,
+		flag:    true,
+	}
+
+	copied := gt.Cast[*myStruct](t, masq.NewMasq(masq.WithContain("blue")).Redact(data))
+	gt.V(t, copied).Equal(data)
+	gt.B(t, copied != data).True()
+
+	// modifying the copy does not affect the original
+	copied.Values[0] = 9
+	copied.ID = 2
+	gt.V(t, data.Values[0]).Equal(1)
+	gt.V(t, data.ID).Equal(1)
+
+	t.Run("scalar is still redacted", func(t *testing.T) {
+		copied := gt.Cast[*myStruct](t, masq.NewMasq(masq.WithFieldName("ID"), masq.WithFieldName("count")).Redact(data))
+		gt.V(t, copied.ID).Equal(0)
+		gt.V(t, copied.count).Equal(0)
+		gt.V(t, copied.Score).Equal(3.14)
+	})
+}
+
+func BenchmarkScalarClone(b *testing.B) {
+	type myStruct struct {
+		ID      int
+		Score   float64
+		Enabled bool
+		Counts  []int
+	}
+	data := &myStruct{
+		ID:      1,
+		Score:   3.14,
+		Enabled: true,
+		Counts:  []int{1, 2, 3, 4, 5, 6, 7, 8},
+	}
+	c := masq.NewMasq(masq.WithContain("blue"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = c.Redact(data)
+	}
+}
+
+func TestMaxNodes(t *testing.T) {
+	type item struct {
+		ID    int
+		Label string
+	}
+	type myStruct struct {
+		Name  string
+		Items []item
+	}
+	data := &myStruct{Name: "orange"}
+	for i := 0; i < 100; i++ {
+		data.Items = append(data.Items, item{ID: i, Label: "five"})
+	}
+
+	t.Run("remainder is truncated", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.New(masq.WithMaxNodes(10)),
+		}))
+		logger.Info("hello", slog.Any("data", data))
+
+		var out struct {
+			Data struct {
+				Name  string
+				Items []any
+			}
+		}
+		gt.NoError(t, json.Unmarshal(buf.Bytes(), &out)).Must()
+		gt.V(t, out.Data.Name).Equal("orange")
+		gt.A(t, out.Data.Items).Length(100).
+			At(0, func(t testing.TB, v any) {
+				gt.V(t, v).Equal(map[string]any{"ID": float64(0), "Label": "five"})
+			}).
+			At(99, func(t testing.TB, v any) {
+				gt.V(t, v).Equal(masq.TruncatedMessage)
+			})
+	})
+
+	t.Run("budget is reset for each redaction", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithMaxNodes(10))
+		for i := 0; i < 3; i++ {
+			copied := gt.Cast[item](t, c.Redact(item{ID: 1, Label: "five"}))
+			gt.V(t, copied.Label).Equal("five")
+		}
+	})
+
+	t.Run("non-positive budget", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Failed to panic")
+			}
+		}()
+		masq.WithMaxNodes(0)
+	})
+}
+
+func TestRedactInto(t *testing.T) {
+	type testData struct {
+		ID    int
+		Name  string
+		Label string
+		tags  []string
+	}
+	c := masq.NewMasq(masq.WithContain("blue"))
+
+	t.Run("redact struct into dst", func(t *testing.T) {
+		src := testData{ID: 100, Name: "blue", Label: "five"}
+		var dst testData
+		gt.NoError(t, c.RedactInto(&dst, src)).Must()
+
+		gt.V(t, dst.ID).Equal(100)
+		gt.V(t, dst.Name).Equal(masq.DefaultRedactMessage)
+		gt.V(t, dst.Label).Equal("five")
+		gt.V(t, src.Name).Equal("blue")
+	})
+
+	t.Run("redact pointer into dst", func(t *testing.T) {
+		src := &testData{ID: 100, Name: "blue", Label: "five", tags: []string{"x"}}
+		var dst testData
+		gt.NoError(t, c.RedactInto(&dst, src)).Must()
+		gt.V(t, dst.Name).Equal(masq.DefaultRedactMessage)
+		gt.V(t, dst.Label).Equal("five")
+		gt.V(t, dst.tags).Equal([]string{"x"})
+		gt.V(t, src.Name).Equal("blue")
+	})
+
+	t.Run("existing data in dst is overwritten", func(t *testing.T) {
+		dst := testData{ID: 1, Name: "orange", Label: "red", tags: []string{"old"}}
+		gt.NoError(t, c.RedactInto(&dst, testData{Name: "blue"})).Must()
+		gt.V(t, dst).Equal(testData{Name: masq.DefaultRedactMessage})
+	})
+
+	t.Run("redact into src itself", func(t *testing.T) {
+		v := testData{ID: 100, Name: "blue", Label: "five", tags: []string{"x"}}
+		gt.NoError(t, c.RedactInto(&v, &v)).Must()
+		gt.V(t, v).Equal(testData{ID: 100, Name: masq.DefaultRedactMessage, Label: "five", tags: []string{"x"}})
+	})
+
+	t.Run("redact string into dst", func(t *testing.T) {
+		var dst string
+		gt.NoError(t, c.RedactInto(&dst, "blue sky")).Must()
+		gt.V(t, dst).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("invalid destination", func(t *testing.T) {
+		var dst testData
+		var nilPtr *testData
+		gt.Error(t, c.RedactInto(dst, testData{})).Is(masq.ErrInvalidDestination)
+		gt.Error(t, c.RedactInto(nilPtr, testData{})).Is(masq.ErrInvalidDestination)
+		gt.Error(t, c.RedactInto(&dst, "blue")).Is(masq.ErrInvalidDestination)
+		gt.Error(t, c.RedactInto(&dst, nil)).Is(masq.ErrInvalidDestination)
+	})
+
+	t.Run("incompatible redaction", func(t *testing.T) {
+		type funcData struct {
+			Func func()
+		}
+		c := masq.NewMasq(masq.WithFieldName("Func"), masq.WithFuncChanMode(masq.FuncChanDescribe))
+		var dst funcData
+		gt.Error(t, c.RedactInto(&dst, funcData{Func: func() {}})).Is(masq.ErrIncompatibleRedaction)
+	})
+}
+
+func TestRedactRedactedValue(t *testing.T) {
+	type testData struct {
+		Name  string
+		Label string
+	}
+	c := masq.NewMasq(
+		masq.WithContain("blue"),
+		masq.WithRegex(regexp.MustCompile(`RED`), masq.MaskWithSymbol('*', 32)),
+	)
+
+	first := gt.Cast[*testData](t, c.Redact(&testData{Name: "blue", Label: "five"}))
+	gt.V(t, first.Name).Equal(masq.DefaultRedactMessage)
+
+	second := gt.Cast[*testData](t, c.Redact(first))
+	gt.V(t, second).Equal(first)
+}
+
+func BenchmarkRedactRedactedValue(b *testing.B) {
+	type testData struct {
+		Name  string
+		Tags  []string
+		Attrs map[string]string
+	}
+	var options []masq.Option
+	for i := 0; i < 32; i++ {
+		options = append(options, masq.WithRegex(regexp.MustCompile(fmt.Sprintf(`^secret-%d-\w+$`, i))))
+	}
+	c := masq.NewMasq(append(options, masq.WithContain("blue"))...)
+
+	data := &testData{
+		Name:  "blue",
+		Tags:  []string{"blue", "blue", "blue"},
+		Attrs: map[string]string{"a": "blue", "b": "blue"},
+	}
+	redacted := c.Redact(data)
+
+	b.Run("first", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = c.Redact(data)
+		}
+	})
+	b.Run("second", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = c.Redact(redacted)
+		}
+	})
+}
+
+func TestStableMapOrder(t *testing.T) {
+	data := map[string]any{}
+	for i := 0; i < 20; i++ {
+		data[fmt.Sprintf("key%02d", i)] = "blue"
+	}
+
+	t.Run("visited in order of keys", func(t *testing.T) {
+		var visited []string
+		c := masq.NewMasq(
+			masq.WithStableMapOrder(),
+			masq.WithCensor(func(fieldName string, value any, tag string) bool {
+				if _, ok := value.(string); ok {
+					visited = append(visited, fieldName)
+				}
+				return false
+			}),
+		)
+		_ = c.Redact(data)
+
+		var expected []string
+		for i := 0; i < 20; i++ {
+			expected = append(expected, fmt.Sprintf("key%02d", i))
+		}
+		gt.V(t, visited).Equal(expected)
+	})
+
+	t.Run("deterministic output across runs", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithStableMapOrder(), masq.WithMaxNodes(11))
+		expected := gt.Cast[map[string]any](t, c.Redact(data))
+		gt.V(t, expected["key09"]).Equal("blue")
+		gt.V(t, expected["key10"]).Equal(masq.TruncatedMessage)
+
+		for i := 0; i < 10; i++ {
+			gt.V(t, gt.Cast[map[string]any](t, c.Redact(data))).Equal(expected)
+		}
+	})
+}
+
+func TestSyncMapSupport(t *testing.T) {
+	type user struct {
+		Name     string
+		Password string `masq:"secret"`
+	}
+	type myRecord struct {
+		Users *sync.Map
+		Cache sync.Map
+	}
+
+	newRecord := func() *myRecord {
+		r := &myRecord{Users: &sync.Map{}}
+		r.Users.Store("alice", user{Name: "alice", Password: "abcd1234"})
+		r.Users.Store("token", "secret-token")
+		r.Cache.Store(1, &user{Name: "bob", Password: "xyz"})
+		return r
+	}
+
+	load := func(m *sync.Map, key any) any {
+		v, ok := m.Load(key)
+		gt.B(t, ok).True()
+		return v
+	}
+
+	t.Run("redact values in sync.Map", func(t *testing.T) {
+		record := newRecord()
+		c := masq.NewMasq(
+			masq.WithSyncMapSupport(),
+			masq.WithTag("secret"),
+			masq.WithFieldName("token"),
+		)
+		copied := gt.Cast[*myRecord](t, c.Redact(record))
+
+		gt.B(t, copied.Users != record.Users).True()
+		gt.V(t, load(copied.Users, "alice")).Equal(any(user{Name: "alice", Password: masq.DefaultRedactMessage}))
+		gt.V(t, load(copied.Users, "token")).Equal(any(masq.DefaultRedactMessage))
+		gt.V(t, gt.Cast[*user](t, load(&copied.Cache, 1)).Password).Equal(masq.DefaultRedactMessage)
+
+		gt.V(t, load(record.Users, "alice")).Equal(any(user{Name: "alice", Password: "abcd1234"}))
+	})
+
+	t.Run("dropped value is removed", func(t *testing.T) {
+		record := newRecord()
+		c := masq.NewMasq(masq.WithSyncMapSupport(), masq.WithFieldName("token", masq.Drop()))
+		copied := gt.Cast[*myRecord](t, c.Redact(record))
+		_, ok := copied.Users.Load("token")
+		gt.B(t, ok).False()
+		load(copied.Users, "alice")
+	})
+}
+
+// genericMap has the same shape as map[string]any, but it's cloned by reflection because the type is different
+type genericMap map[string]any
+
+func TestMapStringAnyFastPath(t *testing.T) {
+	data := map[string]any{
+		"user": map[string]any{
+			"name":     "m-mizutani",
+			"password": "abcd1234",
+			"tags":     []any{"blue", 5, nil},
+		},
+		"token": "secret",
+		"none":  nil,
+	}
+	c := masq.NewMasq(
+		masq.WithFieldName("password"),
+		masq.WithFieldName("token", masq.Drop()),
+		masq.WithContain("blue"),
+	)
+
+	copied := gt.Cast[map[string]any](t, c.Redact(data))
+	gt.V(t, copied).Equal(map[string]any{
+		"user": map[string]any{
+			"name":     "m-mizutani",
+			"password": masq.DefaultRedactMessage,
+			"tags":     []any{masq.DefaultRedactMessage, 5, nil},
+		},
+		"none": nil,
+	})
+
+	generic := gt.Cast[genericMap](t, c.Redact(genericMap(data)))
+	gt.V(t, map[string]any(generic)).Equal(copied)
+
+	gt.V(t, data["user"].(map[string]any)["password"]).Equal("abcd1234")
+}
+
+func BenchmarkMapStringAny(b *testing.B) {
+	data := map[string]any{
+		"id":    "m-mizutani",
+		"email": "mizutani@example.com",
+		"attrs": map[string]any{"color": "blue", "number": 5},
+		"tags":  []any{"a", "b", "c"},
+	}
+	c := masq.NewMasq(masq.WithFieldName("email"), masq.WithContain("blue"))
+
+	b.Run("map[string]any", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = c.Redact(data)
+		}
+	})
+
+	b.Run("named map type", func(b *testing.B) {
+		attrs := genericMap(data["attrs"].(map[string]any))
+		generic := genericMap{
+			"id":    data["id"],
+			"email": data["email"],
+			"attrs": attrs,
+			"tags":  data["tags"],
+		}
+		for i := 0; i < b.N; i++ {
+			_ = c.Redact(generic)
+		}
+	})
+}
+
+func TestMaxMapDepth(t *testing.T) {
+	type myRecord struct {
+		Password string
+		Attrs    map[string]any
+		Labels   map[string]map[string]string
+	}
+	record := myRecord{
+		Password: "abcd1234",
+		Attrs: map[string]any{
+			"Password": "abcd1234",
+			"nested": map[string]any{
+				"Password": "abcd1234",
+			},
+		},
+		Labels: map[string]map[string]string{
+			"env": {"Password": "abcd1234"},
+		},
+	}
+
+	t.Run("deep by default", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("Password"))
+		copied := gt.Cast[myRecord](t, c.Redact(record))
+		gt.V(t, copied.Password).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.Attrs["Password"]).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.Attrs["nested"]).Equal(map[string]any{"Password": masq.DefaultRedactMessage})
+		gt.V(t, copied.Labels["env"]["Password"]).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("shallow", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("Password"), masq.WithMaxMapDepth(1))
+		copied := gt.Cast[myRecord](t, c.Redact(record))
+		gt.V(t, copied.Password).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.Attrs["Password"]).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.Attrs["nested"]).Equal(map[string]any{"Password": "abcd1234"})
+		gt.V(t, copied.Labels["env"]["Password"]).Equal("abcd1234")
+
+		// original map is not modified
+		gt.V(t, record.Attrs["Password"]).Equal("abcd1234")
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Failed to panic")
+			}
+		}()
+		masq.WithMaxMapDepth(0)
+	})
+}
+
+type EmbeddedProfile struct {
+	Bio  string `masq:"secret"`
+	Name string
+}
+
+type embeddedprofile struct {
+	Bio string `masq:"secret"`
+}
+
+func TestEmbeddedPointer(t *testing.T) {
+	type user struct {
+		ID string
+		*EmbeddedProfile
+	}
+	type userWithUnexported struct {
+		ID string
+		*embeddedprofile
+	}
+	c := masq.NewMasq(masq.WithTag("secret"))
+
+	t.Run("redact tagged field of embedded pointer", func(t *testing.T) {
+		src := user{
+			ID:              "m-mizutani",
+			EmbeddedProfile: &EmbeddedProfile{Bio: "my secret", Name: "mizutani"},
+		}
+		copied := gt.Cast[user](t, c.Redact(src))
+		gt.V(t, copied.ID).Equal("m-mizutani")
+		gt.V(t, copied.Bio).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.Name).Equal("mizutani")
+
+		// embedded struct is copied, and the original one is not modified
+		gt.B(t, copied.EmbeddedProfile != src.EmbeddedProfile).True()
+		gt.V(t, src.Bio).Equal("my secret")
+	})
+
+	t.Run("nil embedded pointer", func(t *testing.T) {
+		copied := gt.Cast[user](t, c.Redact(user{ID: "m-mizutani"}))
+		gt.V(t, copied.ID).Equal("m-mizutani")
+		gt.V(t, copied.EmbeddedProfile).Nil()
+	})
+
+	t.Run("pointer to struct with embedded pointer", func(t *testing.T) {
+		copied := gt.Cast[*user](t, c.Redact(&user{
+			ID:              "m-mizutani",
+			EmbeddedProfile: &EmbeddedProfile{Bio: "my secret"},
+		}))
+		gt.V(t, copied.Bio).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("embedded pointer of unexported type", func(t *testing.T) {
+		src := userWithUnexported{
+			ID:              "m-mizutani",
+			embeddedprofile: &embeddedprofile{Bio: "my secret"},
+		}
+		// unexported field of non-addressable struct is not copied except scalar values, then give the pointer
+		copied := gt.Cast[*userWithUnexported](t, c.Redact(&src))
+		gt.V(t, copied.Bio).Equal(masq.DefaultRedactMessage)
+		gt.V(t, src.Bio).Equal("my secret")
+
+		copied = gt.Cast[*userWithUnexported](t, c.Redact(&userWithUnexported{ID: "m-mizutani"}))
+		gt.V(t, copied.embeddedprofile).Nil()
+	})
+
+	t.Run("slog output", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, masq.New(masq.WithTag("secret")))
+		logger.Info("hello",
+			slog.Any("user", user{ID: "m-mizutani", EmbeddedProfile: &EmbeddedProfile{Bio: "my secret", Name: "mizutani"}}),
+			slog.Any("nil", user{ID: "m-mizutani"}),
+		)
+		gt.S(t, buf.String()).
+			Contains(`"user":{"ID":"m-mizutani","Bio":"[REDACTED]","Name":"mizutani"}`).
+			Contains(`"nil":{"ID":"m-mizutani"}`)
+	})
+}
+
+func TestBigNumber(t *testing.T) {
+	type myRecord struct {
+		Int      *big.Int
+		Float    *big.Float
+		Rat      *big.Rat
+		IntValue big.Int
+		Nil      *big.Int
+	}
+	n, ok := new(big.Int).SetString("123456789012345678901234567890", 10)
+	gt.B(t, ok).True()
+	record := myRecord{
+		Int:      n,
+		Float:    big.NewFloat(1.5),
+		Rat:      big.NewRat(1, 3),
+		IntValue: *big.NewInt(42),
+	}
+
+	t.Run("copied with same type", func(t *testing.T) {
+		for _, c := range []*masq.Masq{masq.NewMasq(), masq.NewMasq(masq.WithoutUnsafe())} {
+			copied := gt.Cast[myRecord](t, c.Redact(record))
+			gt.V(t, copied.Int.String()).Equal("123456789012345678901234567890")
+			gt.V(t, copied.Float.String()).Equal("1.5")
+			gt.V(t, copied.Rat.String()).Equal("1/3")
+			gt.V(t, copied.IntValue.String()).Equal("42")
+			gt.V(t, copied.Nil).Nil()
+
+			// copy does not share data with the original value
+			gt.B(t, copied.Int != record.Int).True()
+			copied.Int.SetInt64(1)
+			gt.V(t, record.Int.String()).Equal("123456789012345678901234567890")
+		}
+	})
+
+	t.Run("logged as decimal", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{ReplaceAttr: masq.New()}))
+		logger.Info("hello", slog.Any("record", struct{ Amount *big.Int }{Amount: n}))
+		gt.S(t, buf.String()).Contains(`record={Amount:+123456789012345678901234567890}`)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, masq.New(masq.WithBigNumberString()))
+		logger.Info("hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Int":"123456789012345678901234567890"`).
+			Contains(`"Float":"1.5"`).
+			Contains(`"Rat":"1/3"`).
+			Contains(`"IntValue":"42"`).
+			Contains(`"Nil":null`)
+	})
+
+	t.Run("redacted by filter", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithType[*big.Int](), masq.WithBigNumberString())
+		copied := gt.Cast[map[string]any](t, c.Redact(map[string]any{"amount": n, "rate": big.NewRat(1, 2)}))
+		gt.V(t, copied["amount"]).Equal(any((*big.Int)(nil)))
+		gt.V(t, copied["rate"]).Equal(any("1/2"))
+	})
+}
+
+type taggedInner struct {
+	Secret string `masq:"secret"`
+	Name   string
+}
+
+type taggedError struct {
+	Token string `masq:"secret"`
+}
+
+func (x *taggedError) Error() string { return "tagged error" }
+
+func TestUnexportedInterfaceField(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		inner any
+		ptr   any
+		err   error
+		empty any
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		inner: taggedInner{Secret: "abcd1234", Name: "blue"},
+		ptr:   &taggedInner{Secret: "abcd1234", Name: "orange"},
+		err:   &taggedError{Token: "xyz"},
+	}
+	c := masq.NewMasq(masq.WithTag("secret"))
+
+	check := func(t *testing.T, copied myRecord) {
+		gt.V(t, copied.ID).Equal("m-mizutani")
+
+		inner := gt.Cast[taggedInner](t, copied.inner)
+		gt.V(t, inner).Equal(taggedInner{Secret: masq.DefaultRedactMessage, Name: "blue"})
+
+		ptr := gt.Cast[*taggedInner](t, copied.ptr)
+		gt.V(t, *ptr).Equal(taggedInner{Secret: masq.DefaultRedactMessage, Name: "orange"})
+
+		err := gt.Cast[*taggedError](t, copied.err)
+		gt.V(t, err.Token).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.empty).Nil()
+	}
+
+	t.Run("pointer to struct", func(t *testing.T) {
+		check(t, *gt.Cast[*myRecord](t, c.Redact(&record)))
+	})
+
+	t.Run("original value is not modified", func(t *testing.T) {
+		_ = c.Redact(&record)
+		gt.V(t, record.inner.(taggedInner).Secret).Equal("abcd1234")
+		gt.V(t, record.ptr.(*taggedInner).Secret).Equal("abcd1234")
+		gt.V(t, record.err.(*taggedError).Token).Equal("xyz")
+	})
+
+	t.Run("without unsafe", func(t *testing.T) {
+		// unexported fields can not be read without unsafe, then they are kept nil
+		copied := gt.Cast[myRecord](t, masq.NewMasq(masq.WithTag("secret"), masq.WithoutUnsafe()).Redact(record))
+		gt.V(t, copied.inner).Nil()
+		gt.V(t, copied.ptr).Nil()
+		gt.V(t, copied.err).Nil()
+	})
+}
+
+func TestMapWithStructPointerValues(t *testing.T) {
+	type User struct {
+		ID       string
+		Password string `masq:"secret"`
+	}
+	users := map[string]*User{
+		"alice": {ID: "alice", Password: "abcd1234"},
+		"bob":   {ID: "bob", Password: "xyz"},
+		"nil":   nil,
+	}
+	c := masq.NewMasq(masq.WithTag("secret"))
+
+	t.Run("every entry is redacted", func(t *testing.T) {
+		copied := gt.Cast[map[string]*User](t, c.Redact(users))
+		gt.V(t, *copied["alice"]).Equal(User{ID: "alice", Password: masq.DefaultRedactMessage})
+		gt.V(t, *copied["bob"]).Equal(User{ID: "bob", Password: masq.DefaultRedactMessage})
+		gt.V(t, copied["nil"]).Nil()
+
+		// entries are copied, and the original map is not modified
+		gt.B(t, copied["alice"] != users["alice"]).True()
+		gt.V(t, users["alice"].Password).Equal("abcd1234")
+	})
+
+	t.Run("in struct and slog", func(t *testing.T) {
+		type myRecord struct {
+			Users map[string]*User
+		}
+		var buf bytes.Buffer
+		logger := newLogger(&buf, masq.New(masq.WithTag("secret")))
+		logger.Info("hello", slog.Any("record", myRecord{Users: map[string]*User{
+			"alice": {ID: "alice", Password: "abcd1234"},
+		}}))
+		gt.S(t, buf.String()).Contains(`"Users":{"alice":{"ID":"alice","Password":"[REDACTED]"}}`)
+	})
+}
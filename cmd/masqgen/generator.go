@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"text/template"
+)
+
+var rulesTemplate = template.Must(template.New("rules").Parse(`// Code generated by masqgen. DO NOT EDIT.
+
+package {{ .Package }}
+
+import "github.com/m-mizutani/masq"
+
+// {{ .Var }} is a list of masq options to redact struct fields marked with "{{ .Marker }}" comment.
+var {{ .Var }} = []masq.Option{
+{{- range .Paths }}
+	masq.WithPathGlob({{ printf "%q" . }}),
+{{- end }}
+}
+`))
+
+// generate returns formatted Go source that declares varName as a list of masq.WithPathGlob options. Each path is prefixed with "**" to match the path under any slog group and attribute key.
+func generate(pkgName, varName, marker string, paths []string) ([]byte, error) {
+	patterns := make([]string, len(paths))
+	for i, p := range paths {
+		patterns[i] = "**." + p
+	}
+
+	var buf bytes.Buffer
+	if err := rulesTemplate.Execute(&buf, map[string]any{
+		"Package": pkgName,
+		"Var":     varName,
+		"Marker":  marker,
+		"Paths":   patterns,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render rules: %w", err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return src, nil
+}
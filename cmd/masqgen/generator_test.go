@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+)
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("sample", "MasqRules", "masq:secret", []string{"Email", "Credentials.Password"})
+	gt.NoError(t, err).Must()
+	gt.S(t, string(src)).
+		HasPrefix("// Code generated by masqgen. DO NOT EDIT.\n").
+		Contains("package sample\n").
+		Contains(`masq.WithPathGlob("**.Email"),`).
+		Contains(`masq.WithPathGlob("**.Credentials.Password"),`)
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	sample, err := os.ReadFile("testdata/sample/sample.go")
+	gt.NoError(t, err).Must()
+	gt.NoError(t, os.WriteFile(filepath.Join(dir, "sample.go"), sample, 0o644)).Must()
+
+	cfg := config{
+		dir:     dir,
+		output:  "masq_rules.go",
+		varName: "MasqRules",
+		marker:  "masq:secret",
+	}
+	gt.NoError(t, run(cfg)).Must()
+	// generated file must be ignored in the next run
+	gt.NoError(t, run(cfg)).Must()
+
+	actual, err := os.ReadFile(filepath.Join(dir, "masq_rules.go"))
+	gt.NoError(t, err).Must()
+	expected, err := os.ReadFile("testdata/sample/masq_rules.go")
+	gt.NoError(t, err).Must()
+	gt.V(t, string(actual)).Equal(string(expected))
+}
@@ -0,0 +1,73 @@
+// Command masqgen generates masq options from comments of struct fields. A field that has a comment with the marker "masq:secret" is redacted by the generated options, as an alternative of struct tag.
+//
+//	type User struct {
+//		Name string
+//		// masq:secret
+//		Password string
+//	}
+//
+// Add go:generate directive into the package and run go generate.
+//
+//	//go:generate go run github.com/m-mizutani/masq/cmd/masqgen
+//
+// Then masq_rules.go is generated in the package. It has MasqRules variable that is a list of masq.WithPathGlob options for paths of the marked fields, e.g. "**.Password". The paths start from root struct types, that are struct types not used as a field type of other struct types in the package by default. Use -type option to specify root types explicitly.
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+//		ReplaceAttr: masq.New(MasqRules...),
+//	}))
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type config struct {
+	dir     string
+	output  string
+	varName string
+	marker  string
+	types   []string
+}
+
+func main() {
+	var cfg config
+	var types string
+	flag.StringVar(&cfg.dir, "dir", ".", "directory of the package to scan")
+	flag.StringVar(&cfg.output, "output", "masq_rules.go", "file name to generate in the package directory")
+	flag.StringVar(&cfg.varName, "var", "MasqRules", "name of the generated variable")
+	flag.StringVar(&cfg.marker, "marker", "masq:secret", "marker in field comment to redact the field")
+	flag.StringVar(&types, "type", "", "comma separated names of root struct types (default: struct types not used by other struct types)")
+	flag.Parse()
+
+	if types != "" {
+		cfg.types = strings.Split(types, ",")
+	}
+
+	if err := run(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "masqgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg config) error {
+	pkg, err := parsePackage(cfg.dir, cfg.output)
+	if err != nil {
+		return err
+	}
+
+	paths, err := pkg.paths(cfg.marker, cfg.types)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(pkg.name, cfg.varName, cfg.marker, paths)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(cfg.dir, cfg.output), src, 0o644)
+}
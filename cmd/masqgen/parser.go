@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type pkgInfo struct {
+	name    string
+	structs map[string]*ast.StructType
+}
+
+// parsePackage parses Go files of the package in dir except test files and the output file that is generated by masqgen.
+func parsePackage(dir, output string) (*pkgInfo, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import package in %s: %w", dir, err)
+	}
+
+	pkg := &pkgInfo{
+		name:    bp.Name,
+		structs: map[string]*ast.StructType{},
+	}
+
+	fset := token.NewFileSet()
+	for _, fname := range bp.GoFiles {
+		if fname == output {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filepath.Join(dir, fname), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", fname, err)
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					pkg.structs[ts.Name.Name] = st
+				}
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+// paths returns dotted paths of fields marked with marker from root struct types. If roots is empty, struct types that are not used by other struct types are roots. The returned paths are sorted and unique.
+func (x *pkgInfo) paths(marker string, roots []string) ([]string, error) {
+	if len(roots) == 0 {
+		roots = x.rootTypes()
+	}
+
+	var paths []string
+	for _, root := range roots {
+		st, ok := x.structs[root]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s is not found in package %s", root, x.name)
+		}
+		paths = append(paths, x.walk(st, nil, marker, map[string]bool{root: true}, true)...)
+	}
+
+	sort.Strings(paths)
+	return slices.Compact(paths), nil
+}
+
+// rootTypes returns sorted names of struct types that are not used as field type of other struct types in the package.
+func (x *pkgInfo) rootTypes() []string {
+	used := map[string]bool{}
+	for name, st := range x.structs {
+		x.collectUsed(st, name, used)
+	}
+
+	var roots []string
+	for name := range x.structs {
+		if !used[name] {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+func (x *pkgInfo) collectUsed(st *ast.StructType, self string, used map[string]bool) {
+	for _, field := range st.Fields.List {
+		ref, name, _ := x.structRef(field.Type)
+		switch {
+		case name != "" && name != self:
+			used[name] = true
+		case ref != nil && name == "":
+			x.collectUsed(ref, self, used)
+		}
+	}
+}
+
+// walk returns paths of marked fields in st. If a field refers to a struct type that is being visited, paths of the type are prefixed with "**" to match any depth of the recursive structure when followCycle is true.
+func (x *pkgInfo) walk(st *ast.StructType, prefix []string, marker string, visiting map[string]bool, followCycle bool) []string {
+	var paths []string
+	for _, field := range st.Fields.List {
+		for _, name := range fieldNames(field) {
+			path := append(slices.Clip(prefix), name)
+			if hasMarker(field, marker) {
+				paths = append(paths, strings.Join(path, "."))
+				continue
+			}
+
+			ref, typeName, segments := x.structRef(field.Type)
+			if ref == nil {
+				continue
+			}
+			path = append(path, segments...)
+
+			if typeName != "" && visiting[typeName] {
+				if followCycle {
+					paths = append(paths, x.walk(ref, append(path, "**"), marker, visiting, false)...)
+				}
+				continue
+			}
+
+			if typeName != "" {
+				visiting[typeName] = true
+			}
+			paths = append(paths, x.walk(ref, path, marker, visiting, followCycle)...)
+			if typeName != "" {
+				delete(visiting, typeName)
+			}
+		}
+	}
+	return paths
+}
+
+// structRef returns struct type referred by expr directly or via pointer, slice, array and map value. typeName is empty for anonymous struct. segments are additional path segments to the struct value, e.g. "*" for map key.
+func (x *pkgInfo) structRef(expr ast.Expr) (ref *ast.StructType, typeName string, segments []string) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if st, ok := x.structs[t.Name]; ok {
+			return st, t.Name, nil
+		}
+	case *ast.StructType:
+		return t, "", nil
+	case *ast.StarExpr:
+		return x.structRef(t.X)
+	case *ast.ParenExpr:
+		return x.structRef(t.X)
+	case *ast.ArrayType:
+		return x.structRef(t.Elt)
+	case *ast.MapType:
+		if ref, typeName, segments := x.structRef(t.Value); ref != nil {
+			return ref, typeName, append([]string{"*"}, segments...)
+		}
+	}
+	return nil, "", nil
+}
+
+// fieldNames returns names of the field. Name of embedded field is the type name.
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) > 0 {
+		names := make([]string, len(field.Names))
+		for i, name := range field.Names {
+			names[i] = name.Name
+		}
+		return names
+	}
+
+	expr := field.Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return []string{t.Name}
+	case *ast.SelectorExpr:
+		return []string{t.Sel.Name}
+	}
+	return nil
+}
+
+// hasMarker returns true if doc or line comment of the field has marker as a word. It checks raw comment text because ast.CommentGroup.Text omits directive style comments such as "//masq:secret".
+func hasMarker(field *ast.Field, marker string) bool {
+	for _, group := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			text := strings.TrimPrefix(c.Text, "//")
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "/*"), "*/")
+			if slices.Contains(strings.Fields(text), marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
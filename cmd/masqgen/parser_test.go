@@ -0,0 +1,96 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+)
+
+func TestParsePackage(t *testing.T) {
+	pkg, err := parsePackage("testdata/sample", "masq_rules.go")
+	gt.NoError(t, err).Must()
+	gt.V(t, pkg.name).Equal("sample")
+	gt.M(t, pkg.structs).HaveKey("User").HaveKey("Credentials").HaveKey("Session")
+
+	t.Run("root types", func(t *testing.T) {
+		gt.V(t, pkg.rootTypes()).Equal([]string{"Audit", "User"})
+	})
+
+	t.Run("paths from root types", func(t *testing.T) {
+		paths, err := pkg.paths("masq:secret", nil)
+		gt.NoError(t, err).Must()
+		gt.V(t, paths).Equal([]string{
+			"Credentials.Password",
+			"Credentials.Token",
+			"Devices.*.Serial",
+			"Email",
+			"Profile.Phone",
+			"Sessions.Parent.**.Token",
+			"Sessions.Token",
+		})
+	})
+
+	t.Run("paths from specified root types", func(t *testing.T) {
+		paths, err := pkg.paths("masq:secret", []string{"Session"})
+		gt.NoError(t, err).Must()
+		gt.V(t, paths).Equal([]string{"Parent.**.Token", "Token"})
+	})
+
+	t.Run("unknown root type", func(t *testing.T) {
+		_, err := pkg.paths("masq:secret", []string{"Unknown"})
+		gt.Error(t, err)
+	})
+
+	t.Run("no package", func(t *testing.T) {
+		_, err := parsePackage(t.TempDir(), "masq_rules.go")
+		gt.Error(t, err)
+	})
+}
+
+func TestHasMarker(t *testing.T) {
+	testCases := map[string]struct {
+		src    string
+		expect bool
+	}{
+		"doc comment": {
+			src:    "struct {\n// masq:secret\nF string\n}",
+			expect: true,
+		},
+		"line comment": {
+			src:    "struct {\nF string // masq:secret\n}",
+			expect: true,
+		},
+		"directive style": {
+			src:    "struct {\nF string //masq:secret\n}",
+			expect: true,
+		},
+		"block comment": {
+			src:    "struct {\nF string /* masq:secret */\n}",
+			expect: true,
+		},
+		"marker in sentence": {
+			src:    "struct {\n// Password of the user. masq:secret\nF string\n}",
+			expect: true,
+		},
+		"different marker": {
+			src:    "struct {\nF string // masq:secretive\n}",
+			expect: false,
+		},
+		"no comment": {
+			src:    "struct {\nF string\n}",
+			expect: false,
+		},
+	}
+
+	for title, tc := range testCases {
+		t.Run(title, func(t *testing.T) {
+			f, err := parser.ParseFile(token.NewFileSet(), "", "package p\ntype T "+tc.src, parser.ParseComments)
+			gt.NoError(t, err).Must()
+			st := gt.Cast[*ast.StructType](t, f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type)
+			gt.V(t, hasMarker(st.Fields.List[0], "masq:secret")).Equal(tc.expect)
+		})
+	}
+}
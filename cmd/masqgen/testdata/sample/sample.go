@@ -0,0 +1,38 @@
+package sample
+
+type User struct {
+	ID   string
+	Name string
+	// masq:secret
+	Email       string
+	Credentials Credentials
+	Sessions    []*Session
+	Devices     map[string]Device
+	Profile     struct {
+		Nickname string
+		Phone    string // masq:secret
+	}
+}
+
+type Credentials struct {
+	Username string
+	/* masq:secret */
+	Password string
+	Token    string // API token. masq:secret
+}
+
+type Session struct {
+	ID     string
+	Token  string //masq:secret
+	Parent *Session
+}
+
+type Device struct {
+	Name   string
+	Serial string // masq:secret
+}
+
+type Audit struct {
+	Credentials
+	Actor string
+}
@@ -0,0 +1,65 @@
+package masq
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Config is a declarative set of redaction rules, e.g. loaded from a config file of YAML or JSON. FromConfig converts it into options.
+type Config struct {
+	// FieldNames are field names to be redacted. See WithFieldName.
+	FieldNames []string `json:"field_names" yaml:"field_names"`
+	// FieldPrefixes are prefixes of field names to be redacted. See WithFieldPrefix.
+	FieldPrefixes []string `json:"field_prefixes" yaml:"field_prefixes"`
+	// Tags are values of struct tag to be redacted. See WithTag.
+	Tags []string `json:"tags" yaml:"tags"`
+	// Contains are strings to redact string values containing them. See WithContain.
+	Contains []string `json:"contains" yaml:"contains"`
+	// Regexes are regular expressions to redact string values matched with them. See WithRegex.
+	Regexes []string `json:"regexes" yaml:"regexes"`
+	// AllowedTypes are fully qualified names of types that are not redacted, e.g. "time.Time". See WithAllowedTypeFullName.
+	AllowedTypes []string `json:"allowed_types" yaml:"allowed_types"`
+	// RedactMessage is a message to replace redacted string. If it's empty, DefaultRedactMessage is used. See WithRedactMessage.
+	RedactMessage string `json:"redact_message" yaml:"redact_message"`
+}
+
+// Validate returns an error if the config can not be converted into options, e.g. invalid regular expression.
+func (x Config) Validate() error {
+	for _, expr := range x.Regexes {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("masq: invalid regex in config %q: %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// FromConfig converts cfg into options. It panics if cfg is invalid, then call Config.Validate before FromConfig for config given by users.
+func FromConfig(cfg Config) []Option {
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+
+	var options []Option
+	for _, name := range cfg.FieldNames {
+		options = append(options, WithFieldName(name))
+	}
+	for _, prefix := range cfg.FieldPrefixes {
+		options = append(options, WithFieldPrefix(prefix))
+	}
+	for _, tag := range cfg.Tags {
+		options = append(options, WithTag(tag))
+	}
+	for _, target := range cfg.Contains {
+		options = append(options, WithContain(target))
+	}
+	for _, expr := range cfg.Regexes {
+		options = append(options, WithRegex(regexp.MustCompile(expr)))
+	}
+	if len(cfg.AllowedTypes) > 0 {
+		options = append(options, WithAllowedTypeFullName(cfg.AllowedTypes...))
+	}
+	if cfg.RedactMessage != "" {
+		options = append(options, WithRedactMessage(cfg.RedactMessage))
+	}
+	return options
+}
@@ -0,0 +1,85 @@
+package masq_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/m-mizutani/masq"
+)
+
+func TestFromConfig(t *testing.T) {
+	raw := `{
+		"field_names": ["Password"],
+		"field_prefixes": ["Secret"],
+		"tags": ["secret"],
+		"contains": ["blue"],
+		"regexes": ["^\\d{3}-\\d{4}-\\d{4}$"],
+		"allowed_types": ["time.Time"],
+		"redact_message": "(hidden)"
+	}`
+	var cfg masq.Config
+	gt.NoError(t, json.Unmarshal([]byte(raw), &cfg))
+	gt.NoError(t, cfg.Validate())
+
+	type myRecord struct {
+		ID         string
+		Password   string
+		SecretKey  string
+		Token      string `masq:"secret"`
+		Color      string
+		Phone      string
+		CreatedAt  time.Time
+		InternalID string
+	}
+	now := time.Now()
+	record := myRecord{
+		ID:         "m-mizutani",
+		Password:   "abcd1234",
+		SecretKey:  "xyz",
+		Token:      "token",
+		Color:      "blue",
+		Phone:      "090-0000-0000",
+		CreatedAt:  now,
+		InternalID: "123",
+	}
+
+	c := masq.NewMasq(masq.FromConfig(cfg)...)
+	copied := gt.Cast[myRecord](t, c.Redact(record))
+	gt.V(t, copied).Equal(myRecord{
+		ID:         "m-mizutani",
+		Password:   "(hidden)",
+		SecretKey:  "(hidden)",
+		Token:      "(hidden)",
+		Color:      "(hidden)",
+		Phone:      "(hidden)",
+		CreatedAt:  now,
+		InternalID: "123",
+	})
+
+	t.Run("allowed type", func(t *testing.T) {
+		c := masq.NewMasq(masq.FromConfig(masq.Config{
+			FieldNames:   []string{"CreatedAt"},
+			AllowedTypes: []string{"time.Time"},
+		})...)
+		copied := gt.Cast[myRecord](t, c.Redact(record))
+		gt.V(t, copied.CreatedAt).Equal(now)
+	})
+
+	t.Run("empty config", func(t *testing.T) {
+		gt.A(t, masq.FromConfig(masq.Config{})).Length(0)
+	})
+
+	t.Run("invalid regex", func(t *testing.T) {
+		cfg := masq.Config{Regexes: []string{"("}}
+		gt.Error(t, cfg.Validate())
+
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Failed to panic")
+			}
+		}()
+		masq.FromConfig(cfg)
+	})
+}
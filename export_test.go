@@ -1,9 +1,8 @@
 package masq
 
-func NewMasq(options ...Option) *masq {
-	return newMasq(options...)
-}
+import "log/slog"
 
-func (x *masq) Redact(v any) any {
-	return x.redact("", v)
+// LogInMasq logs msg from package masq. It's used to test WithCallerPackageRules with a caller package other than masq_test.
+func LogInMasq(logger *slog.Logger, msg string, args ...any) {
+	logger.Info(msg, args...)
 }
@@ -0,0 +1,230 @@
+package masq
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type handler struct {
+	inner   slog.Handler
+	masq    *Masq
+	options []Option
+	groups  []string
+
+	// callerMasqs caches Masq for each key of WithCallerPackageRules. It's shared with handlers created by WithAttrs and WithGroup.
+	callerMasqs *sync.Map
+}
+
+// NewHandler wraps the inner slog.Handler and redacts attributes of a record in Handle and attributes given by WithAttrs. Attributes in a group are also redacted. It can be used instead of New when the inner handler already has its own ReplaceAttr function. Options attached to the context of the record by WithContextOptions and options of WithCallerPackageRules for the package that logs the record are added to options for the record.
+func NewHandler(inner slog.Handler, options ...Option) slog.Handler {
+	return &handler{
+		inner:       inner,
+		masq:        NewMasq(options...),
+		options:     options,
+		callerMasqs: &sync.Map{},
+	}
+}
+
+type ctxKeyOptions struct{}
+
+// WithContextOptions returns a copy of ctx that carries options to tighten redaction of one-off logs, e.g. a log of a request that has a sensitive parameter, without creating a new logger. NewHandler and NewHandlerWithContext add the options to their own options for records logged with the context, e.g. by InfoContext. Options are accumulated if ctx already has options. Attributes given by WithAttrs of NewHandler are redacted in advance and not affected. New can not read the options because ReplaceAttr of slog does not receive the context.
+func WithContextOptions(ctx context.Context, options ...Option) context.Context {
+	return context.WithValue(ctx, ctxKeyOptions{}, append(slices.Clip(contextOptions(ctx)), options...))
+}
+
+func contextOptions(ctx context.Context) []Option {
+	options, _ := ctx.Value(ctxKeyOptions{}).([]Option)
+	return options
+}
+
+func (x *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return x.inner.Enabled(ctx, level)
+}
+
+func (x *handler) Handle(ctx context.Context, r slog.Record) error {
+	pkg, rule := x.masq.callerRule(r.PC)
+	if options := contextOptions(ctx); len(options) > 0 {
+		h := *x
+		h.masq = NewMasq(slices.Concat(x.options, rule, options)...)
+		x = &h
+	} else if rule != nil {
+		h := *x
+		h.masq = x.callerMasq(pkg, rule)
+		x = &h
+	}
+	return x.handle(ctx, r)
+}
+
+// callerMasq returns Masq with options of the handler and rule for the key pkg of WithCallerPackageRules.
+func (x *handler) callerMasq(pkg string, rule []Option) *Masq {
+	if m, ok := x.callerMasqs.Load(pkg); ok {
+		return m.(*Masq)
+	}
+	m, _ := x.callerMasqs.LoadOrStore(pkg, NewMasq(slices.Concat(x.options, rule)...))
+	return m.(*Masq)
+}
+
+// callerRule returns the key and options of WithCallerPackageRules that match the package of the function at pc. pc is a program counter of slog.Record. It returns nil options if no key matches.
+func (x *Masq) callerRule(pc uintptr) (string, []Option) {
+	if len(x.callerRules) == 0 || pc == 0 {
+		return "", nil
+	}
+
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	pkg := funcPackage(frame.Function)
+	var matched string
+	var found bool
+	for key := range x.callerRules {
+		if pkg != key && !strings.HasPrefix(pkg, strings.TrimSuffix(key, "/")+"/") {
+			continue
+		}
+		if !found || len(key) > len(matched) {
+			matched, found = key, true
+		}
+	}
+	if !found {
+		return "", nil
+	}
+	return matched, x.callerRules[matched]
+}
+
+// funcPackage returns the import path of package from the fully qualified function name, e.g. "github.com/acme/app.(*Server).Serve" to "github.com/acme/app".
+func funcPackage(name string) string {
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// handle redacts attributes of r with x.masq without options in ctx, and passes the redacted record to the inner handler.
+func (x *handler) handle(ctx context.Context, r slog.Record) error {
+	newRecord := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		newRecord.AddAttrs(x.redactAttr(x.groups, attr))
+		return true
+	})
+
+	return x.inner.Handle(ctx, newRecord)
+}
+
+func (x *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = x.redactAttr(x.groups, attr)
+	}
+
+	return &handler{
+		inner:       x.inner.WithAttrs(redacted),
+		masq:        x.masq,
+		options:     x.options,
+		groups:      x.groups,
+		callerMasqs: x.callerMasqs,
+	}
+}
+
+func (x *handler) WithGroup(name string) slog.Handler {
+	return &handler{
+		inner:       x.inner.WithGroup(name),
+		masq:        x.masq,
+		options:     x.options,
+		groups:      append(slices.Clip(x.groups), name),
+		callerMasqs: x.callerMasqs,
+	}
+}
+
+func (x *handler) redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Value.Kind() == slog.KindGroup {
+		// attributes of group with empty key are inlined into the parent group by slog
+		if attr.Key != "" {
+			groups = append(slices.Clip(groups), attr.Key)
+		}
+
+		group := attr.Value.Group()
+		redacted := make([]any, len(group))
+		for i, a := range group {
+			redacted[i] = x.redactAttr(groups, a)
+		}
+		return slog.Group(attr.Key, redacted...)
+	}
+
+	return x.masq.redactAttr(groups, attr)
+}
+
+// Policy is a function to select options of redaction for a record by the context given to the logger, e.g. rules of the tenant of the request.
+type Policy func(ctx context.Context) []Option
+
+type contextHandler struct {
+	inner  slog.Handler
+	policy Policy
+	steps  []handlerStep
+}
+
+// handlerStep is an operation of WithAttrs or WithGroup that is replayed for each record. If group is empty, the step is WithAttrs.
+type handlerStep struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewHandlerWithContext wraps the inner slog.Handler and redacts attributes of a record with options selected by policy from the context of the record. It's useful when the redaction rules vary for each request, e.g. in multi-tenant system, because ReplaceAttr of slog does not receive the context. Use slog.Logger methods with context such as InfoContext to pass the context. Options are built for each record with options attached by WithContextOptions, and attributes given by WithAttrs are kept as is and redacted for each record with the options. Then it's slower than NewHandler.
+func NewHandlerWithContext(inner slog.Handler, policy Policy) slog.Handler {
+	return &contextHandler{
+		inner:  inner,
+		policy: policy,
+	}
+}
+
+func (x *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return x.inner.Enabled(ctx, level)
+}
+
+func (x *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	policy, ctxOptions := x.policy(ctx), contextOptions(ctx)
+	m := NewMasq(slices.Concat(policy, ctxOptions)...)
+	if _, rule := m.callerRule(r.PC); rule != nil {
+		m = NewMasq(slices.Concat(policy, rule, ctxOptions)...)
+	}
+	var h slog.Handler = &handler{
+		inner: x.inner,
+		masq:  m,
+	}
+
+	for _, step := range x.steps {
+		if step.group != "" {
+			h = h.WithGroup(step.group)
+		} else {
+			h = h.WithAttrs(step.attrs)
+		}
+	}
+
+	// options in ctx and caller rules are already merged
+	return h.(*handler).handle(ctx, r)
+}
+
+func (x *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return x
+	}
+	return x.with(handlerStep{attrs: attrs})
+}
+
+func (x *contextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return x
+	}
+	return x.with(handlerStep{group: name})
+}
+
+func (x *contextHandler) with(step handlerStep) *contextHandler {
+	return &contextHandler{
+		inner:  x.inner,
+		policy: x.policy,
+		steps:  append(slices.Clip(x.steps), step),
+	}
+}
@@ -0,0 +1,320 @@
+package masq_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/m-mizutani/masq"
+)
+
+func TestNewHandler(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Phone string
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		Phone: "090-0000-0000",
+	}
+
+	var buf bytes.Buffer
+	inner := slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		// custom ReplaceAttr of user
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.String(slog.TimeKey, "2022-12-25")
+			}
+			return a
+		},
+	})
+	logger := slog.New(masq.NewHandler(inner, masq.WithFieldName("Phone"), masq.WithContain("blue")))
+
+	t.Run("redact record attribute", func(t *testing.T) {
+		buf.Reset()
+		logger.Info("hello", slog.Any("record", record), slog.String("color", "five"))
+		gt.S(t, buf.String()).
+			Contains(`"time":"2022-12-25"`).
+			Contains(`"record":{"ID":"m-mizutani","Phone":"[REDACTED]"}`).
+			Contains(`"color":"five"`).
+			NotContains("090-0000-0000")
+	})
+
+	t.Run("redact attribute by With", func(t *testing.T) {
+		buf.Reset()
+		logger.With("record", record).With("color", "blue").Info("hello")
+		gt.S(t, buf.String()).
+			Contains(`"time":"2022-12-25"`).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"color":"[REDACTED]"`).
+			NotContains("090-0000-0000")
+	})
+
+	t.Run("redact attribute in group", func(t *testing.T) {
+		buf.Reset()
+		logger.WithGroup("g1").Info("hello",
+			slog.Group("g2", slog.Any("record", record), slog.String("color", "blue")),
+		)
+		gt.S(t, buf.String()).
+			Contains(`"g1":{"g2":{"record":{"ID":"m-mizutani","Phone":"[REDACTED]"},"color":"[REDACTED]"}}`)
+	})
+
+	t.Run("redact attribute of LogValuer", func(t *testing.T) {
+		buf.Reset()
+		logger.Info("hello", slog.Any("group", logValuer{}))
+		gt.S(t, buf.String()).
+			Contains(`"group":{"color":"[REDACTED]","number":"five"}`)
+	})
+}
+
+func TestNewHandlerWithGroups(t *testing.T) {
+	var buf bytes.Buffer
+	var calledGroups [][]string
+	censor := func(groups []string, fieldName string, value any, tag string) bool {
+		if fieldName == "color" {
+			calledGroups = append(calledGroups, slices.Clone(groups))
+		}
+		return len(groups) > 0 && groups[len(groups)-1] == "secret"
+	}
+	logger := slog.New(masq.NewHandler(slog.NewJSONHandler(&buf, nil), masq.WithGroupCensor(censor)))
+
+	logger.WithGroup("g1").With("color", "five").WithGroup("secret").Info("hello",
+		slog.String("color", "blue"),
+		slog.Group("g2", slog.String("color", "orange")),
+		slog.Group("", slog.String("color", "red")),
+	)
+
+	gt.S(t, buf.String()).
+		Contains(`"g1":{"color":"five","secret":{"color":"[REDACTED]","g2":{"color":"orange"},"color":"[REDACTED]"}}`)
+	gt.V(t, calledGroups).Equal([][]string{
+		{"g1"},
+		{"g1", "secret"},
+		{"g1", "secret", "g2"},
+		{"g1", "secret"},
+	})
+}
+
+type ctxKeyTenant struct{}
+
+func TestNewHandlerWithContext(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Phone string
+		Email string
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		Phone: "090-0000-0000",
+		Email: "mizutani@example.com",
+	}
+
+	policy := func(ctx context.Context) []masq.Option {
+		switch ctx.Value(ctxKeyTenant{}) {
+		case "tenant-a":
+			return []masq.Option{masq.WithFieldName("Phone")}
+		case "tenant-b":
+			return []masq.Option{masq.WithFieldName("Email"), masq.WithRedactMessage("[masked]")}
+		default:
+			return []masq.Option{masq.WithFieldName("Phone"), masq.WithFieldName("Email")}
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(masq.NewHandlerWithContext(slog.NewJSONHandler(&buf, nil), policy))
+	ctxA := context.WithValue(context.Background(), ctxKeyTenant{}, "tenant-a")
+	ctxB := context.WithValue(context.Background(), ctxKeyTenant{}, "tenant-b")
+
+	t.Run("tenants get different rules", func(t *testing.T) {
+		buf.Reset()
+		logger.InfoContext(ctxA, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"mizutani@example.com"`)
+
+		buf.Reset()
+		logger.InfoContext(ctxB, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Phone":"090-0000-0000"`).
+			Contains(`"Email":"[masked]"`)
+	})
+
+	t.Run("default rule without tenant", func(t *testing.T) {
+		buf.Reset()
+		logger.Info("hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"[REDACTED]"`)
+	})
+
+	t.Run("attributes and groups by With are redacted by rule of record", func(t *testing.T) {
+		child := logger.With("record", record).WithGroup("g1").With("Phone", "090-1111-1111")
+
+		buf.Reset()
+		child.InfoContext(ctxA, "hello", slog.String("Email", "a@example.com"))
+		gt.S(t, buf.String()).
+			Contains(`"record":{"ID":"m-mizutani","Phone":"[REDACTED]","Email":"mizutani@example.com"}`).
+			Contains(`"g1":{"Phone":"[REDACTED]","Email":"a@example.com"}`)
+
+		buf.Reset()
+		child.InfoContext(ctxB, "hello", slog.String("Email", "b@example.com"))
+		gt.S(t, buf.String()).
+			Contains(`"record":{"ID":"m-mizutani","Phone":"090-0000-0000","Email":"[masked]"}`).
+			Contains(`"g1":{"Phone":"090-1111-1111","Email":"[masked]"}`)
+	})
+}
+
+func TestWithContextOptions(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Phone string
+		Email string
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		Phone: "090-0000-0000",
+		Email: "mizutani@example.com",
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(masq.NewHandler(slog.NewJSONHandler(&buf, nil), masq.WithFieldName("Phone")))
+
+	t.Run("context option redacts allowed field", func(t *testing.T) {
+		ctx := masq.WithContextOptions(context.Background(), masq.WithFieldName("Email"))
+
+		buf.Reset()
+		logger.InfoContext(ctx, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"[REDACTED]"`)
+
+		buf.Reset()
+		logger.Info("hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"mizutani@example.com"`)
+	})
+
+	t.Run("options are accumulated", func(t *testing.T) {
+		ctx := masq.WithContextOptions(context.Background(), masq.WithFieldName("Email"))
+		ctx = masq.WithContextOptions(ctx, masq.WithFieldName("ID"))
+
+		buf.Reset()
+		logger.WithGroup("g").InfoContext(ctx, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"g":{"record":{"ID":"[REDACTED]","Phone":"[REDACTED]","Email":"[REDACTED]"}}`)
+	})
+
+	t.Run("NewHandlerWithContext", func(t *testing.T) {
+		logger := slog.New(masq.NewHandlerWithContext(slog.NewJSONHandler(&buf, nil), func(ctx context.Context) []masq.Option {
+			return []masq.Option{masq.WithFieldName("Phone")}
+		}))
+		ctx := masq.WithContextOptions(context.Background(), masq.WithFieldName("Email"))
+
+		buf.Reset()
+		logger.InfoContext(ctx, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"[REDACTED]"`)
+	})
+}
+
+func TestCallerPackageRules(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Phone string
+		Email string
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		Phone: "090-0000-0000",
+		Email: "mizutani@example.com",
+	}
+
+	rules := masq.WithCallerPackageRules(map[string][]masq.Option{
+		"github.com/m-mizutani/masq":      {masq.WithFieldName("Email")},
+		"github.com/m-mizutani/masq_test": {masq.WithFieldName("ID")},
+	})
+
+	t.Run("NewHandler", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(masq.NewHandler(slog.NewJSONHandler(&buf, nil), masq.WithFieldName("Phone"), rules))
+
+		masq.LogInMasq(logger, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"m-mizutani"`).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"[REDACTED]"`)
+
+		buf.Reset()
+		logger.With("user", "m-mizutani").Info("hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"user":"m-mizutani"`).
+			Contains(`"ID":"[REDACTED]"`).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"mizutani@example.com"`)
+	})
+
+	t.Run("with context options", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(masq.NewHandler(slog.NewJSONHandler(&buf, nil), rules))
+		ctx := masq.WithContextOptions(context.Background(), masq.WithFieldName("Phone"))
+
+		logger.InfoContext(ctx, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"[REDACTED]"`).
+			Contains(`"Phone":"[REDACTED]"`).
+			Contains(`"Email":"mizutani@example.com"`)
+	})
+
+	t.Run("NewHandlerWithContext", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(masq.NewHandlerWithContext(slog.NewJSONHandler(&buf, nil), func(ctx context.Context) []masq.Option {
+			return []masq.Option{rules}
+		}))
+
+		masq.LogInMasq(logger, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"m-mizutani"`).
+			Contains(`"Email":"[REDACTED]"`)
+
+		buf.Reset()
+		logger.Info("hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"[REDACTED]"`).
+			Contains(`"Email":"mizutani@example.com"`)
+	})
+
+	t.Run("longest key of parent package is used", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(masq.NewHandler(slog.NewJSONHandler(&buf, nil), masq.WithCallerPackageRules(map[string][]masq.Option{
+			"github.com/m-mizutani":      {masq.WithFieldName("ID")},
+			"github.com/m-mizutani/masq": {masq.WithFieldName("Email")},
+		})))
+
+		masq.LogInMasq(logger, "hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"m-mizutani"`).
+			Contains(`"Email":"[REDACTED]"`)
+
+		buf.Reset()
+		logger.Info("hello", slog.Any("record", record))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"[REDACTED]"`).
+			Contains(`"Email":"mizutani@example.com"`)
+	})
+
+	t.Run("record without program counter", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := masq.NewHandler(slog.NewJSONHandler(&buf, nil), rules)
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0)
+		r.AddAttrs(slog.Any("record", record))
+		gt.NoError(t, h.Handle(context.Background(), r))
+		gt.S(t, buf.String()).
+			Contains(`"ID":"m-mizutani"`).
+			Contains(`"Email":"mizutani@example.com"`)
+	})
+}
@@ -1,8 +1,16 @@
 package masq
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"log/slog"
 )
@@ -13,60 +21,672 @@ const (
 
 	// DefaultRedactMessage is a default message to replace redacted value. WithRedactMessage option can change this value.
 	DefaultRedactMessage = "[REDACTED]"
+
+	// MaskedUUID is a message to replace UUID redacted by the default redactor with WithUUIDBytes option. It keeps the canonical form of UUID for log parsers that expect it.
+	MaskedUUID = "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"
+
+	// TruncatedMessage is a message to replace values that are not visited because the number of visited values exceeds the limit set by WithMaxNodes option.
+	TruncatedMessage = "<truncated>"
+
+	// CycleMessage is a message to replace map and slice that contain themselves, e.g. m["self"] = m for m of map[string]any. The cycle is kept in the redacted value as this message instead of recursing until the depth limit.
+	CycleMessage = "<cycle>"
+)
+
+var (
+	// ErrInvalidDestination is returned by RedactInto when dst is not a non-nil pointer to the type of src.
+	ErrInvalidDestination = errors.New("masq: destination must be a non-nil pointer to the type of source")
+
+	// ErrIncompatibleRedaction is returned by RedactInto when the redacted value can not be stored into the destination because its type is changed by redaction.
+	ErrIncompatibleRedaction = errors.New("masq: redacted value is incompatible with destination type")
+
+	// ErrNotRedacted is returned by RedactE and RedactInto in strict mode enabled by WithStrict when values matched by filters are not actually redacted.
+	ErrNotRedacted = errors.New("masq: sensitive value is not redacted")
 )
 
-type masq struct {
-	redactMessage string
-	filters       []*Filter
-	allowedTypes  map[reflect.Type]struct{}
+// Redactable is an interface for types that define their own redaction. If a value implements Redactable and no filter matches it, masq uses the value returned by Redacted as the redacted value instead of copying it. The returned value must be convertible into the type of the value, otherwise the value is copied as usual. It's enabled by default, and WithRedactableInterface can disable it.
+type Redactable interface {
+	Redacted() any
+}
+
+// Masq is a redaction engine configured by options. It's created by NewMasq. Usually, New is enough to use masq with slog, but Masq can be used to redact a value directly.
+type Masq struct {
+	redactMessage    string
+	filters          []*Filter
+	allowedTypes     map[reflect.Type]struct{}
+	allowedTypeNames map[string]struct{}
+	allowedValues    map[string]struct{}
+	allowTags        map[string]struct{}
+	skipFieldNames   map[string]struct{}
+	typeCloners      map[reflect.Type]func(src any) any
+	stringifyTypes   map[reflect.Type]struct{}
+	mapKeyCensors    Censors
+	attrKeyFilters   map[string]*Filter
+	callerRules      map[string][]Option
+
+	tagKey       string
+	funcChanMode FuncChanMode
+
+	redactMapStructKeys bool
+	maxNodes            int
+	maxMapDepth         int
+	jsonSafe            bool
+	stableMapOrder      bool
+	pathRequired        bool
+	byteArrayAsString   bool
+	durationString      bool
+	uuidBytes           bool
+	bigNumberString     bool
+	nonZeroOnly         bool
+	leafOnly            bool
+	tagCascade          bool
+	attrScoped          bool
+	dryRun              bool
+	goStringRedaction   bool
+	pathInMessage       bool
+	tagOptionsEnabled   bool
+	strict              bool
+	lengthHint          bool
+	tagDirectiveEnabled bool
+	tagDirectiveKey     string
+	withoutUnsafe       bool
+	syncMapSupport      bool
+	structFieldRequired bool
+	parentRequired      bool
+	preserveUncloneable bool
+	protoSafe           bool
+	reflectDescribe     bool
+	jsonRoundTrip       bool
+	orderedStructOutput bool
+	jsonPassthrough     bool
+	redactableDisabled  bool
+	atomicsCopied       bool
+
+	kindRedactValues map[reflect.Kind]reflect.Value
+	metrics          func(rule string)
 
-	defaultRedactor Redactor
-	tagKey          string
+	topLevelRedactors filterRedactors
+
+	// disabled is an immutable set of names of filters disabled by SetEnabled. It's replaced with a new set on every change, then redaction reads it without lock. It's nil if no filter has been disabled.
+	disabled      atomic.Pointer[map[string]struct{}]
+	disabledMutex sync.Mutex
+
+	// active is Masq configured by Reconfigure. If it's nil, Masq itself is used.
+	active atomic.Pointer[Masq]
 }
 
 type Filter struct {
-	censor    Censor
-	redactors Redactors
+	name      string
+	censor    filterCensor
+	redactors filterRedactors
+
+	// tag is true if the filter is added by WithTag. Leaves of containers matched by it are redacted with WithTagCascade option.
+	tag bool
+
+	// find returns index pairs of substrings in s matched by the filter, e.g. by WithContain and WithRegex. Writer redacts only the substrings with it. It's nil if the filter matches a value as a whole.
+	find func(s string) [][]int
+}
+
+// filterCensor is a censor that can access the state of the current redaction, such as slog groups, through context.
+type filterCensor func(ctx context.Context, fieldName string, value any, tag string) bool
+
+type ctxKeyGroups struct{}
+
+// ctxKeyRootAttr is a key of context to hold the key of the top-level slog attribute for WithAttrScope option. It's the outermost group name if the attribute is in a group.
+type ctxKeyRootAttr struct{}
+
+func rootAttrFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(ctxKeyRootAttr{}).(string)
+	return key
 }
 
-type Option func(m *masq)
+// groupsFromContext returns slog group names of the attribute that is being redacted.
+func groupsFromContext(ctx context.Context) []string {
+	groups, _ := ctx.Value(ctxKeyGroups{}).([]string)
+	return groups
+}
+
+type Option func(m *Masq)
+
+// NewMasq creates a new Masq with options.
+func NewMasq(options ...Option) *Masq {
+	m := &Masq{
+		redactMessage:   DefaultRedactMessage,
+		jsonPassthrough: true,
+		allowedTypes:    map[reflect.Type]struct{}{},
+		allowedValues:   map[string]struct{}{},
+		allowTags:       map[string]struct{}{},
+		tagKey:          DefaultTagKey,
 
-func newMasq(options ...Option) *masq {
-	m := &masq{
-		redactMessage: DefaultRedactMessage,
-		allowedTypes:  map[reflect.Type]struct{}{},
-		tagKey:        DefaultTagKey,
+		kindRedactValues: map[reflect.Kind]reflect.Value{},
 	}
-	m.defaultRedactor = func(src, dst reflect.Value) bool {
-		switch src.Kind() {
-		case reflect.String:
-			dst.Elem().SetString(m.redactMessage)
+	for _, opt := range options {
+		opt(m)
+	}
+
+	return m
+}
+
+// redactDefault redacts src by the default redactor that is applied if no redactor of the filter redacts src. String is replaced with the redact message and other values are left as zero value.
+func (x *Masq) redactDefault(call *redactCall, src reflect.Value) {
+	if v, ok := x.kindRedactValues[src.Kind()]; ok {
+		if redacted, ok := convertRedactValue(v, src.Type()); ok {
+			call.dst.Elem().Set(redacted)
+			return
 		}
+	}
+
+	if x.uuidBytes && isUUIDBytes(src.Type()) {
+		call.replaceWith(reflect.ValueOf(MaskedUUID))
+		return
+	}
+
+	switch src.Kind() {
+	case reflect.String:
+		call.dst.Elem().SetString(x.redactStringAt(src.String(), call.info.path))
+	}
+}
+
+// Reconfigure replaces all rules of Masq with options at once, e.g. to reload config of a long-lived service. Loggers that use ReplaceAttr method of the Masq pick up the new rules without recreating the logger. Options given to NewMasq and the previous Reconfigure are discarded, and filters disabled by SetEnabled are enabled again. It's safe to call Reconfigure while other goroutines are redacting values. A redaction that has already started is completed with the previous rules.
+func (x *Masq) Reconfigure(options ...Option) {
+	x.active.Store(NewMasq(options...))
+}
+
+// current returns Masq that has the current rules.
+func (x *Masq) current() *Masq {
+	if m := x.active.Load(); m != nil {
+		return m
+	}
+	return x
+}
+
+// SetEnabled enables or disables filters that have the name at runtime. The name is given by WithNamedCensor, or by other options such as "WithTag:secret". All filters are enabled by default. It's safe to call SetEnabled while other goroutines are redacting values, and the change is applied to values visited after the call.
+func (x *Masq) SetEnabled(name string, enabled bool) {
+	x = x.current()
+	x.disabledMutex.Lock()
+	defer x.disabledMutex.Unlock()
+
+	var current map[string]struct{}
+	if p := x.disabled.Load(); p != nil {
+		current = *p
+	}
+	if _, disabled := current[name]; disabled != enabled {
+		return
+	}
+
+	next := make(map[string]struct{}, len(current)+1)
+	for k := range current {
+		next[k] = struct{}{}
+	}
+	if enabled {
+		delete(next, name)
+	} else {
+		next[name] = struct{}{}
+	}
+	if len(next) == 0 {
+		x.disabled.Store(nil)
+		return
+	}
+	x.disabled.Store(&next)
+}
+
+func (x *Masq) isEnabled(filter *Filter) bool {
+	p := x.disabled.Load()
+	if p == nil {
 		return true
 	}
+	_, disabled := (*p)[filter.name]
+	return !disabled
+}
 
-	for _, opt := range options {
-		opt(m)
+// redactString returns the redact message to replace s. If WithLengthHint is enabled, the message has the length of s, e.g. "[REDACTED:12]".
+func (x *Masq) redactString(s string) string {
+	return x.redactStringAt(s, "")
+}
+
+// redactStringAt returns the redact message to replace s at path. If WithPathInMessage is enabled and path is not empty, the message has the path, e.g. "[REDACTED:User.Password]". The length by WithLengthHint follows the path, e.g. "[REDACTED:User.Password:12]".
+func (x *Masq) redactStringAt(s, path string) string {
+	var notes []string
+	if x.pathInMessage && path != "" {
+		notes = append(notes, path)
+	}
+	if x.lengthHint {
+		notes = append(notes, strconv.Itoa(len(s)))
+	}
+	if len(notes) == 0 {
+		return x.redactMessage
 	}
+	prefix, suffix := x.messageAffixes()
+	return prefix + strings.Join(notes, ":") + suffix
+}
 
-	return m
+// messageAffixes returns prefix and suffix of the redact message with notes such as length hint and path. The notes are inserted before the trailing "]" of the message if it exists, or appended to the message.
+func (x *Masq) messageAffixes() (string, string) {
+	if msg, ok := strings.CutSuffix(x.redactMessage, "]"); ok {
+		return msg + ":", "]"
+	}
+	return x.redactMessage + ":", ""
 }
 
-func (x *masq) redact(k string, v any) any {
-	if v == nil {
-		return nil
+// noteOfMessage returns notes in s if s is the redact message with notes.
+func (x *Masq) noteOfMessage(s string) (string, bool) {
+	prefix, suffix := x.messageAffixes()
+	n, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return "", false
+	}
+	if n, ok = strings.CutSuffix(n, suffix); !ok || n == "" {
+		return "", false
+	}
+	return n, true
+}
+
+// isLengthHinted returns true if s is the redact message with length hint.
+func (x *Masq) isLengthHinted(s string) bool {
+	n, ok := x.noteOfMessage(s)
+	return ok && strings.Trim(n, "0123456789") == ""
+}
+
+// convertRedactValue converts v set by WithKindRedactMessage to t. Empty slice and map of any type are converted into empty value of t. Number is not converted into string to avoid unexpected conversion to rune.
+func convertRedactValue(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	switch {
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Map) && v.Kind() == t.Kind() && v.Len() == 0:
+		if t.Kind() == reflect.Slice {
+			return reflect.MakeSlice(t, 0, 0), true
+		}
+		return reflect.MakeMap(t), true
+
+	case t.Kind() == reflect.String && v.Kind() != reflect.String:
+		return reflect.Value{}, false
+
+	case v.CanConvert(t):
+		return v.Convert(t), true
+	}
+
+	return reflect.Value{}, false
+}
+
+// FuncChanMode is a mode to specify how to redact func and chan value that is matched with a filter. WithFuncChanMode option can change the mode.
+type FuncChanMode int
+
+const (
+	// FuncChanZero replaces func and chan value with nil. It is the default mode.
+	FuncChanZero FuncChanMode = iota
+	// FuncChanPreserve keeps the original func and chan value.
+	FuncChanPreserve
+	// FuncChanDescribe replaces func and chan value with a placeholder string, "<func>" or "<chan>". If the value is in a struct field, slice, array or map, the container is converted into a new struct type that has the string field, []any, [N]any or map[K]any.
+	FuncChanDescribe
+)
+
+func (x *Masq) redactFuncChan(src reflect.Value) (reflect.Value, bool) {
+	if src.Kind() == reflect.Interface && !src.IsNil() {
+		src = src.Elem()
+	}
+	if src.Kind() != reflect.Func && src.Kind() != reflect.Chan {
+		return reflect.Value{}, false
+	}
+
+	switch x.funcChanMode {
+	case FuncChanPreserve:
+		return src, true
+	case FuncChanDescribe:
+		return reflect.ValueOf("<" + src.Kind().String() + ">"), true
+	default:
+		return reflect.Value{}, false
+	}
+}
+
+// Redact returns a redacted copy of v. The original value v is not modified. In strict mode enabled by WithStrict, it panics if values matched by filters are not redacted. Use RedactE to get the error instead.
+func (x *Masq) Redact(v any) any {
+	redacted, err := x.RedactE(v)
+	if err != nil {
+		panic(err)
+	}
+	return redacted
+}
+
+// RedactE returns a redacted copy of v in the same way as Redact. In strict mode enabled by WithStrict, it returns the redacted copy and an error wrapping ErrNotRedacted if values matched by filters are not redacted. Otherwise, the error is always nil.
+func (x *Masq) RedactE(v any) (any, error) {
+	redacted, err := x.current().redact(nil, "", v, nil)
+	if _, ok := redacted.(dropped); ok {
+		return nil, err
+	}
+	return redacted, err
+}
+
+// Report describes what is done by RedactWithReport.
+type Report struct {
+	// Redacted is a list of values redacted by filters in the order of redaction.
+	Redacted []ReportEntry
+	// Uncloneable is a list of paths of func, chan and unsafe.Pointer values that are not matched by any filter and copied as is because they can not be cloned.
+	Uncloneable []string
+}
+
+// ReportEntry is a value redacted by a filter. Path is the dotted path of the value from the root, e.g. "Credentials.Password". Path is empty for the root value. Elements of slice and array have the same path as their parent. Rule is the filter name, e.g. "WithFieldName:Password".
+type ReportEntry struct {
+	Path string
+	Rule string
+}
+
+// RedactWithReport returns a redacted copy of v in the same way as Redact, and a report of redacted values and uncloneable values. It's useful to audit masq rules in tests or CI. Building paths of values costs additional allocations, then it's not recommended to use in hot paths. In strict mode enabled by WithStrict, it panics if values matched by filters are not redacted.
+func (x *Masq) RedactWithReport(v any) (any, Report) {
+	x = x.current()
+
+	var report Report
+	redacted, err := x.redact(nil, "", v, &report)
+	if err != nil {
+		panic(err)
+	}
+	if _, ok := redacted.(dropped); ok {
+		return nil, report
+	}
+	return redacted, report
+}
+
+// RedactInto redacts src and stores the redacted copy into dst. dst must be a non-nil pointer to the type of src, or the same pointer type as src. If src is a struct, the fields are copied into dst directly without allocating a new struct unless dst and src are the same pointer. Existing data in dst is overwritten. It returns ErrInvalidDestination if dst is not acceptable, and ErrIncompatibleRedaction if the redacted value can not be stored into dst because the type is changed by redaction, e.g. with FuncChanDescribe mode.
+func (x *Masq) RedactInto(dst, src any) error {
+	x = x.current()
+	dstValue := reflect.ValueOf(dst)
+	if src == nil || dstValue.Kind() != reflect.Ptr || dstValue.IsNil() {
+		return ErrInvalidDestination
+	}
+
+	srcValue := reflect.ValueOf(src)
+	if srcValue.Type() == dstValue.Type() {
+		if srcValue.IsNil() {
+			dstValue.Elem().SetZero()
+			return nil
+		}
+		srcValue = srcValue.Elem()
+	}
+	if srcValue.Type() != dstValue.Elem().Type() {
+		return ErrInvalidDestination
+	}
+
+	into := dstValue.Elem()
+	if srcValue.CanAddr() && srcValue.Addr().Pointer() == dstValue.Pointer() {
+		// dst is zeroed before fields of src are read if they share memory, e.g. RedactInto(&v, &v). Then clone into a new value and set it into dst
+		into = reflect.Value{}
+	}
+
+	ctx := x.newContext(nil, "", nil)
+	copied := undrop(x.cloneInto(ctx, "", srcValue, "", into), srcValue.Type())
+	if copied.CanAddr() && copied.Addr().Pointer() == dstValue.Pointer() {
+		// fields are already copied into dst
+		return strictReportFromContext(ctx).err()
 	}
+	if !copied.Type().AssignableTo(dstValue.Elem().Type()) {
+		return ErrIncompatibleRedaction
+	}
+	dstValue.Elem().Set(copied)
+	return strictReportFromContext(ctx).err()
+}
 
+func (x *Masq) newContext(groups []string, k string, report *Report) context.Context {
 	ctx := context.Background()
-	copied := x.clone(ctx, k, reflect.ValueOf(v), "")
-	return copied.Interface()
+	if len(groups) > 0 {
+		ctx = context.WithValue(ctx, ctxKeyGroups{}, groups)
+	}
+	if report != nil {
+		ctx = context.WithValue(ctx, ctxKeyReport{}, report)
+	}
+	if x.attrScoped {
+		root := k
+		if len(groups) > 0 {
+			root = groups[0]
+		}
+		ctx = context.WithValue(ctx, ctxKeyRootAttr{}, root)
+	}
+	if x.pathRequired || report != nil {
+		ctx = context.WithValue(ctx, ctxKeyPath{}, strings.Join(append(slices.Clip(groups), k), "."))
+	}
+	if x.maxNodes > 0 {
+		ctx = context.WithValue(ctx, ctxKeyNodes{}, new(int))
+	}
+	if x.strict {
+		ctx = context.WithValue(ctx, ctxKeyStrict{}, &strictReport{})
+	}
+	return ctx
+}
+
+func (x *Masq) redact(groups []string, k string, v any, report *Report) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	ctx := x.newContext(groups, k, report)
+	src := reflect.ValueOf(v)
+	var copied reflect.Value
+	if x.jsonRoundTrip {
+		ctx, src, copied = x.cloneWithJSONFallback(ctx, groups, k, src)
+	} else {
+		copied = x.clone(ctx, k, src, "")
+	}
+	if len(x.topLevelRedactors) > 0 && !x.dryRun && !isBuiltinKey(groups, k) {
+		copied = x.redactTopLevel(src, copied)
+	}
+	return copied.Interface(), strictReportFromContext(ctx).err()
+}
+
+// cloneWithJSONFallback clones src in the same way as clone. If clone panics, src is converted into map[string]any, []any or scalar value via JSON marshaling and unmarshaling, and the converted value is cloned with a new context instead. It returns the context, the source value and the cloned value that are actually used. If src can not be marshaled into JSON, it panics with the original error of clone.
+func (x *Masq) cloneWithJSONFallback(ctx context.Context, groups []string, k string, src reflect.Value) (context.Context, reflect.Value, reflect.Value) {
+	cloned, recovered := x.tryClone(ctx, k, src)
+	if recovered == nil {
+		return ctx, src, cloned
+	}
+
+	raw, err := json.Marshal(src.Interface())
+	if err != nil {
+		panic(recovered)
+	}
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil || decoded == nil {
+		panic(recovered)
+	}
+
+	ctx = x.newContext(groups, k, reportFromContext(ctx))
+	src = reflect.ValueOf(decoded)
+	copied := x.clone(ctx, k, src, "")
+	if x.orderedStructOutput {
+		copied = reflect.ValueOf(orderJSONObjects(copied.Interface(), raw))
+	}
+	return ctx, src, copied
+}
+
+// tryClone clones src and returns the recovered value if clone panics.
+func (x *Masq) tryClone(ctx context.Context, k string, src reflect.Value) (copied reflect.Value, recovered any) {
+	defer func() {
+		recovered = recover()
+	}()
+	return x.clone(ctx, k, src, ""), nil
+}
+
+// redactTopLevel applies redactors set by WithTopLevelRedactor to the root value if it's a scalar and it's not redacted by filters.
+func (x *Masq) redactTopLevel(src, copied reflect.Value) reflect.Value {
+	switch src.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+	default:
+		return copied
+	}
+	if copied.Type() != src.Type() || !copied.Equal(src) {
+		// already redacted by filters
+		return copied
+	}
+
+	call := newRedactCall(x, src.Type(), redactInfo{})
+	if !x.topLevelRedactors.redact(call, src) {
+		return copied
+	}
+	if call.replacement.IsValid() {
+		return call.replacement
+	}
+	return call.dst.Elem()
+}
+
+// isBuiltinKey returns true if k is a key of built-in attribute of slog, that are time, level, message and source.
+func isBuiltinKey(groups []string, k string) bool {
+	if len(groups) > 0 {
+		return false
+	}
+	switch k {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+		return true
+	}
+	return false
+}
+
+// redactAttr redacts value of attr. It panics if values are not redacted in strict mode.
+func (x *Masq) redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	x = x.current()
+	var masked any
+	if filter, ok := x.attrKeyFilters[attr.Key]; ok && attr.Value.Any() != nil && x.isEnabled(filter) {
+		masked = x.redactByAttrKey(filter, attr.Value.Any())
+	} else {
+		var err error
+		if masked, err = x.redact(groups, attr.Key, attr.Value.Any(), nil); err != nil {
+			panic(err)
+		}
+	}
+	if _, ok := masked.(dropped); ok {
+		// slog ignores an empty attribute
+		return slog.Attr{}
+	}
+	return slog.Any(attr.Key, masked)
+}
+
+// redactByAttrKey redacts v of the attribute whose key is matched by WithAttrKey without visiting its content.
+func (x *Masq) redactByAttrKey(filter *Filter, v any) any {
+	if x.metrics != nil {
+		x.metrics(filter.name)
+	}
+	if x.dryRun {
+		return v
+	}
+	return x.applyFilter(context.Background(), filter, reflect.ValueOf(v), "", "").Interface()
 }
 
 func New(options ...Option) func(groups []string, a slog.Attr) slog.Attr {
-	m := newMasq(options...)
+	return NewMasq(options...).ReplaceAttr
+}
+
+// ReplaceAttr redacts the value of attr. It can be given to ReplaceAttr of slog.HandlerOptions in the same way as the function returned by New, and it's useful to keep Masq to call Reconfigure or SetEnabled later.
+func (x *Masq) ReplaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	return x.redactAttr(groups, attr)
+}
 
+// Chain returns a ReplaceAttr function of slog that applies fns in order. Each function receives the attribute returned by the previous one. Use it with the function returned by New to compose masq with other ReplaceAttr functions, e.g. formatting time. The order matters: functions before masq see the original value and masq redacts what they return, and functions after masq see only the redacted value. If a function returns an empty attribute, slog drops it and the remaining functions are not called.
+func Chain(fns ...func(groups []string, attr slog.Attr) slog.Attr) func(groups []string, attr slog.Attr) slog.Attr {
 	return func(groups []string, attr slog.Attr) slog.Attr {
-		masked := m.redact(attr.Key, attr.Value.Any())
-		return slog.Any(attr.Key, masked)
+		for _, fn := range fns {
+			attr = fn(groups, attr)
+			if attr.Equal(slog.Attr{}) {
+				return attr
+			}
+		}
+		return attr
+	}
+}
+
+// OrderedMap is a JSON object whose entries are in the order of the original struct fields. It's used instead of map[string]any in the fallback of WithJSONRoundTrip with WithOrderedStructOutput option. Key of each entry is string.
+type OrderedMap []MapEntry
+
+// MarshalJSON encodes the entries as a JSON object in order.
+func (x OrderedMap) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, entry := range x {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, key...), ':'), value...)
+	}
+	return append(buf, '}'), nil
+}
+
+// LogValue returns a slog group of the entries in order.
+func (x OrderedMap) LogValue() slog.Value {
+	attrs := make([]slog.Attr, 0, len(x))
+	for _, entry := range x {
+		key, _ := entry.Key.(string)
+		attrs = append(attrs, slog.Any(key, entry.Value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// orderJSONObjects converts map[string]any in v into OrderedMap with the key order of raw, that is JSON representation of v before redaction. Keys that are not in v, e.g. dropped by redaction, are skipped, and keys that are not in raw are appended in sorted order. Values that are not JSON object or array anymore, e.g. replaced with the redact message, are returned as is.
+func orderJSONObjects(v any, raw json.RawMessage) any {
+	switch v := v.(type) {
+	case map[string]any:
+		keys, values, ok := decodeJSONObject(raw)
+		if !ok {
+			return v
+		}
+		ordered := make(OrderedMap, 0, len(v))
+		for i, key := range keys {
+			value, exists := v[key]
+			if !exists {
+				continue
+			}
+			delete(v, key)
+			ordered = append(ordered, MapEntry{Key: key, Value: orderJSONObjects(value, values[i])})
+		}
+		// keys changed by redaction, e.g. WithMapKeyValueRedaction, are not in raw
+		rest := make([]string, 0, len(v))
+		for key := range v {
+			rest = append(rest, key)
+		}
+		slices.Sort(rest)
+		for _, key := range rest {
+			ordered = append(ordered, MapEntry{Key: key, Value: v[key]})
+		}
+		return ordered
+
+	case []any:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil || len(elems) != len(v) {
+			return v
+		}
+		for i := range v {
+			v[i] = orderJSONObjects(v[i], elems[i])
+		}
+		return v
+	}
+	return v
+}
+
+// decodeJSONObject returns keys and raw values of JSON object in raw in order.
+func decodeJSONObject(raw json.RawMessage) ([]string, []json.RawMessage, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return nil, nil, false
+	}
+
+	var keys []string
+	var values []json.RawMessage
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, nil, false
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, nil, false
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, false
+		}
+		keys = append(keys, key)
+		values = append(values, value)
 	}
+	return keys, values, true
 }
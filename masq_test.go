@@ -1,10 +1,15 @@
 package masq_test
 
 import (
+	"bytes"
 	"os"
+	"slices"
+	"sync"
+	"testing"
 
 	"log/slog"
 
+	"github.com/m-mizutani/gt"
 	"github.com/m-mizutani/masq"
 )
 
@@ -25,3 +30,265 @@ func Example() {
 
 	logger.Info("hello", slog.Any("user", u))
 }
+
+func TestNestedGroups(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Phone string
+	}
+	record := myRecord{
+		ID:    "m-mizutani",
+		Phone: "090-0000-0000",
+	}
+
+	t.Run("attributes in nested groups are redacted", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.New(masq.WithFieldName("Phone"), masq.WithContain("blue")),
+		}))
+
+		logger.WithGroup("g1").With("color", "blue").WithGroup("g2").Info("hello",
+			slog.Group("g3",
+				slog.Any("record", record),
+				slog.Group("g4", slog.String("color", "blue")),
+			),
+		)
+		gt.S(t, buf.String()).
+			Contains(`"g1":{"color":"[REDACTED]","g2":{"g3":{"record":{"ID":"m-mizutani","Phone":"[REDACTED]"},"g4":{"color":"[REDACTED]"}}}}`).
+			NotContains("090-0000-0000").
+			NotContains("blue")
+	})
+
+	t.Run("groups are passed to censor", func(t *testing.T) {
+		var buf bytes.Buffer
+		var calledGroups [][]string
+		censor := func(groups []string, fieldName string, value any, tag string) bool {
+			if fieldName == "color" {
+				calledGroups = append(calledGroups, slices.Clone(groups))
+			}
+			return len(groups) == 2 && groups[1] == "secret"
+		}
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.New(masq.WithGroupCensor(censor)),
+		}))
+
+		logger.WithGroup("g1").Info("hello",
+			slog.Group("secret", slog.String("color", "blue")),
+			slog.Group("public", slog.String("color", "orange")),
+		)
+		gt.S(t, buf.String()).
+			Contains(`"secret":{"color":"[REDACTED]"}`).
+			Contains(`"public":{"color":"orange"}`)
+		gt.V(t, calledGroups).Equal([][]string{{"g1", "secret"}, {"g1", "public"}})
+	})
+}
+
+func TestChain(t *testing.T) {
+	renameTime := func(groups []string, attr slog.Attr) slog.Attr {
+		if attr.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.String("timestamp", "2022-12-25")
+		}
+		return attr
+	}
+
+	t.Run("combine masq with other function", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.Chain(renameTime, masq.New(masq.WithContain("blue"))),
+		}))
+		logger.Info("hello", slog.String("color", "blue"), slog.String("number", "five"))
+		gt.S(t, buf.String()).
+			Contains(`"timestamp":"2022-12-25"`).
+			NotContains(`"time":`).
+			Contains(`"color":"[REDACTED]"`).
+			Contains(`"number":"five"`)
+	})
+
+	t.Run("later function sees redacted value", func(t *testing.T) {
+		var seen []string
+		record := func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == "color" {
+				seen = append(seen, attr.Value.String())
+			}
+			return attr
+		}
+
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.Chain(record, masq.New(masq.WithContain("blue")), record),
+		}))
+		logger.Info("hello", slog.String("color", "blue"))
+		gt.V(t, seen).Equal([]string{"blue", masq.DefaultRedactMessage})
+	})
+
+	t.Run("stop at dropped attribute", func(t *testing.T) {
+		var called bool
+		c := masq.Chain(
+			masq.New(masq.WithFieldName("color", masq.Drop())),
+			func(groups []string, attr slog.Attr) slog.Attr {
+				called = true
+				return attr
+			},
+		)
+		gt.V(t, c(nil, slog.String("color", "blue"))).Equal(slog.Attr{})
+		gt.B(t, called).False()
+	})
+}
+
+func TestSetEnabled(t *testing.T) {
+	type myRecord struct {
+		ID       string
+		Password string `masq:"secret"`
+	}
+	record := myRecord{ID: "m-mizutani", Password: "abcd1234"}
+
+	m := masq.NewMasq(
+		masq.WithNamedCensor("id", func(fieldName string, value any, tag string) bool {
+			return fieldName == "ID"
+		}),
+		masq.WithTag("secret"),
+	)
+
+	copied := gt.Cast[myRecord](t, m.Redact(record))
+	gt.V(t, copied.ID).Equal(masq.DefaultRedactMessage)
+	gt.V(t, copied.Password).Equal(masq.DefaultRedactMessage)
+
+	m.SetEnabled("id", false)
+	m.SetEnabled("WithTag:secret", false)
+	copied = gt.Cast[myRecord](t, m.Redact(record))
+	gt.V(t, copied.ID).Equal("m-mizutani")
+	gt.V(t, copied.Password).Equal("abcd1234")
+
+	m.SetEnabled("id", true)
+	copied = gt.Cast[myRecord](t, m.Redact(record))
+	gt.V(t, copied.ID).Equal(masq.DefaultRedactMessage)
+	gt.V(t, copied.Password).Equal("abcd1234")
+
+	// unknown name is ignored
+	m.SetEnabled("unknown", false)
+	copied = gt.Cast[myRecord](t, m.Redact(record))
+	gt.V(t, copied.ID).Equal(masq.DefaultRedactMessage)
+}
+
+func TestReconfigure(t *testing.T) {
+	type myRecord struct {
+		ID       string
+		Password string
+		Phone    string
+	}
+	record := myRecord{ID: "m-mizutani", Password: "abcd1234", Phone: "090-0000-0000"}
+
+	m := masq.NewMasq(masq.WithFieldName("Password"))
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: m.ReplaceAttr,
+	}))
+
+	logger.Info("hello", slog.Any("record", record))
+	gt.S(t, buf.String()).
+		Contains(`"Password":"[REDACTED]"`).
+		Contains(`"Phone":"090-0000-0000"`)
+
+	m.Reconfigure(masq.WithFieldName("Phone"), masq.WithRedactMessage("(hidden)"))
+	buf.Reset()
+	logger.Info("hello", slog.Any("record", record))
+	gt.S(t, buf.String()).
+		Contains(`"Password":"abcd1234"`).
+		Contains(`"Phone":"(hidden)"`)
+
+	copied := gt.Cast[myRecord](t, m.Redact(record))
+	gt.V(t, copied.Phone).Equal("(hidden)")
+
+	t.Run("SetEnabled applies to new rules", func(t *testing.T) {
+		m.SetEnabled("WithFieldName:Phone", false)
+		copied := gt.Cast[myRecord](t, m.Redact(record))
+		gt.V(t, copied.Phone).Equal("090-0000-0000")
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				m.Reconfigure(masq.WithFieldName("Password"))
+			}()
+			go func() {
+				defer wg.Done()
+				_ = m.Redact(record)
+			}()
+		}
+		wg.Wait()
+		copied := gt.Cast[myRecord](t, m.Redact(record))
+		gt.V(t, copied.Password).Equal(masq.DefaultRedactMessage)
+	})
+}
+
+func TestRedactWithReport(t *testing.T) {
+	type credentials struct {
+		Password string
+		Token    string
+	}
+	type myRecord struct {
+		ID      string
+		Phone   string
+		Cred    credentials
+		Tags    []string
+		Notify  func()
+		Headers map[string]string
+	}
+	record := myRecord{
+		ID:      "m-mizutani",
+		Phone:   "090-0000-0000",
+		Cred:    credentials{Password: "abcd1234", Token: "xyz"},
+		Tags:    []string{"admin", "secret-tag"},
+		Notify:  func() {},
+		Headers: map[string]string{"Authorization": "Bearer xyz"},
+	}
+
+	m := masq.NewMasq(
+		masq.WithFieldName("Password"),
+		masq.WithFieldName("Authorization"),
+		masq.WithContain("secret"),
+	)
+	redacted, report := m.RedactWithReport(record)
+
+	copied := gt.Cast[myRecord](t, redacted)
+	gt.V(t, copied.Cred.Password).Equal(masq.DefaultRedactMessage)
+	gt.V(t, copied.Cred.Token).Equal("xyz")
+	gt.V(t, copied.Tags[1]).Equal(masq.DefaultRedactMessage)
+	gt.V(t, copied.Headers["Authorization"]).Equal(masq.DefaultRedactMessage)
+	gt.V(t, report.Redacted).Equal([]masq.ReportEntry{
+		{Path: "Cred.Password", Rule: "WithFieldName:Password"},
+		{Path: "Tags", Rule: "WithContain:secret"},
+		{Path: "Headers.Authorization", Rule: "WithFieldName:Authorization"},
+	})
+	gt.V(t, report.Uncloneable).Equal([]string{"Notify"})
+
+	t.Run("report is not shared with Redact", func(t *testing.T) {
+		_ = m.Redact(record)
+		_, report := m.RedactWithReport(credentials{Password: "abcd1234"})
+		gt.V(t, report.Redacted).Equal([]masq.ReportEntry{
+			{Path: "Password", Rule: "WithFieldName:Password"},
+		})
+		gt.A(t, report.Uncloneable).Length(0)
+	})
+
+	t.Run("Redact does not build paths after RedactWithReport", func(t *testing.T) {
+		fresh := masq.NewMasq(
+			masq.WithFieldName("Password"),
+			masq.WithFieldName("Authorization"),
+			masq.WithContain("secret"),
+		)
+		expected := testing.AllocsPerRun(10, func() { _ = fresh.Redact(record) })
+		gt.V(t, testing.AllocsPerRun(10, func() { _ = m.Redact(record) })).Equal(expected)
+	})
+
+	t.Run("root value", func(t *testing.T) {
+		redacted, report := m.RedactWithReport("my secret")
+		gt.V(t, redacted).Equal(masq.DefaultRedactMessage)
+		gt.V(t, report.Redacted).Equal([]masq.ReportEntry{
+			{Path: "", Rule: "WithContain:secret"},
+		})
+	})
+}
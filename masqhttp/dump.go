@@ -0,0 +1,97 @@
+// Package masqhttp provides helpers to redact HTTP messages dumped by net/http/httputil, such as httputil.DumpRequest, before logging them.
+//
+//	redact := masqhttp.RedactDump(masq.WithRegex(regexp.MustCompile(`token=\w+`)))
+//	dump, _ := httputil.DumpRequest(r, true)
+//	logger.Info("request", slog.String("dump", string(redact(dump))))
+package masqhttp
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/textproto"
+
+	"github.com/m-mizutani/masq"
+)
+
+// SensitiveHeaders are headers that are always redacted by RedactDump.
+var SensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+var (
+	crlf      = []byte("\r\n")
+	separator = []byte("\r\n\r\n")
+)
+
+// RedactDump returns a function to redact a dump of HTTP request or response. Values of SensitiveHeaders are always redacted. Additionally, options are applied to the request line, each header value and the body. A header value is redacted with the canonical header name as field name, e.g. "X-Api-Key", then censors of field name such as masq.WithFieldName and masq.WithAttrKey can be used for headers, and a header dropped by masq.Drop is removed. The request line and the body are redacted as a whole string without field name, then use censors of string value such as masq.WithContain and masq.WithRegex for them. The returned function can be used concurrently.
+func RedactDump(options ...masq.Option) func(dump []byte) []byte {
+	var opts []masq.Option
+	for _, name := range SensitiveHeaders {
+		opts = append(opts, masq.WithFieldName(name))
+	}
+	m := masq.NewMasq(append(opts, options...)...)
+
+	return func(dump []byte) []byte {
+		head, body, hasBody := bytes.Cut(dump, separator)
+		lines := bytes.Split(head, crlf)
+
+		var buf bytes.Buffer
+		for i, line := range lines {
+			if i == 0 {
+				if redacted, ok := redactString(m, "", string(line)); ok {
+					buf.WriteString(redacted)
+				}
+				continue
+			}
+
+			name, value, ok := bytes.Cut(line, []byte(":"))
+			if !ok {
+				buf.Write(crlf)
+				buf.Write(line)
+				continue
+			}
+			key := textproto.CanonicalMIMEHeaderKey(string(bytes.TrimSpace(name)))
+			redacted, ok := redactString(m, key, string(bytes.TrimSpace(value)))
+			if !ok {
+				// the header is dropped by masq.Drop
+				continue
+			}
+			buf.Write(crlf)
+			buf.Write(name)
+			buf.WriteString(": ")
+			buf.WriteString(redacted)
+		}
+
+		if hasBody {
+			buf.Write(separator)
+			if len(body) > 0 {
+				if redacted, ok := redactString(m, "", string(body)); ok {
+					buf.WriteString(redacted)
+				}
+			}
+		}
+		return buf.Bytes()
+	}
+}
+
+// redactString redacts s with key as field name. If key is empty, s is redacted as a top-level value. It returns false if s is dropped by masq.Drop.
+func redactString(m *masq.Masq, key, s string) (string, bool) {
+	if key == "" {
+		redacted := m.Redact(s)
+		if redacted == nil {
+			return "", false
+		}
+		return fmt.Sprint(redacted), true
+	}
+
+	// key of slog attribute is given to censors as field name
+	attr := m.ReplaceAttr(nil, slog.String(key, s))
+	if attr.Equal(slog.Attr{}) {
+		return "", false
+	}
+	return fmt.Sprint(attr.Value.Any()), true
+}
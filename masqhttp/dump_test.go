@@ -0,0 +1,62 @@
+package masqhttp_test
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+	"github.com/m-mizutani/masq"
+	"github.com/m-mizutani/masq/masqhttp"
+)
+
+func TestRedactDump(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/login?token=abcd1234", strings.NewReader(`{"password":"blue"}`))
+	gt.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer xyz-secret")
+	req.Header.Set("Cookie", "session=s3cr3t")
+	req.Header.Set("X-Api-Key", "my-api-key")
+	req.Header.Set("User-Agent", "masq-test")
+
+	dump, err := httputil.DumpRequest(req, true)
+	gt.NoError(t, err)
+
+	redact := masqhttp.RedactDump(
+		masq.WithFieldName("X-Api-Key"),
+		masq.WithRegex(regexp.MustCompile(`token=\w+`)),
+		masq.WithContain("password"),
+	)
+	redacted := string(redact(dump))
+
+	gt.S(t, redacted).
+		HasPrefix("[REDACTED]\r\nHost: example.com\r\n").
+		Contains("Authorization: [REDACTED]\r\n").
+		Contains("Cookie: [REDACTED]\r\n").
+		Contains("X-Api-Key: [REDACTED]\r\n").
+		Contains("User-Agent: masq-test\r\n").
+		Contains("\r\n\r\n[REDACTED]")
+	gt.S(t, redacted).NotContains("xyz-secret")
+	gt.S(t, redacted).NotContains("s3cr3t")
+	gt.S(t, redacted).NotContains("my-api-key")
+	gt.S(t, redacted).NotContains("abcd1234")
+	gt.S(t, redacted).NotContains("blue")
+
+	t.Run("no option", func(t *testing.T) {
+		redacted := string(masqhttp.RedactDump()(dump))
+		gt.S(t, redacted).
+			Contains("POST /login?token=abcd1234 HTTP/1.1").
+			Contains("Authorization: [REDACTED]\r\n").
+			Contains("X-Api-Key: my-api-key\r\n").
+			Contains(`{"password":"blue"}`)
+	})
+
+	t.Run("dropped header is removed", func(t *testing.T) {
+		redacted := string(masqhttp.RedactDump(masq.WithFieldName("Host", masq.Drop()))(dump))
+		gt.S(t, redacted).
+			HasPrefix("POST /login?token=abcd1234 HTTP/1.1\r\nAuthorization: [REDACTED]\r\n").
+			NotContains("Host").
+			NotContains("<nil>")
+	})
+}
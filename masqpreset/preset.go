@@ -0,0 +1,46 @@
+// Package masqpreset provides masq options to redact secret struct types of popular libraries, such as oauth2.Token, tls.Certificate and AWS credentials. The types are matched by fully qualified type name, then the libraries are not imported by this package.
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+//		ReplaceAttr: masq.New(masqpreset.All()...),
+//	}))
+package masqpreset
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/m-mizutani/masq"
+)
+
+// Type is an option to redact the fields of the struct type that has the fully qualified type name, e.g. "golang.org/x/oauth2.Token". The fields in fields are redacted by the default redactor of masq, and unexported fields are also redacted because they may hold secrets, e.g. raw response of token endpoint. Other exported fields are cloned and checked by other options as usual.
+func Type(fullName string, fields ...string) masq.Option {
+	return masq.WithTypeField(fullName, func(field reflect.StructField) bool {
+		return !field.IsExported() || slices.Contains(fields, field.Name)
+	})
+}
+
+// OAuth2Token is an option to redact AccessToken and RefreshToken of golang.org/x/oauth2.Token. TokenType and Expiry are kept.
+func OAuth2Token() masq.Option {
+	return Type("golang.org/x/oauth2.Token", "AccessToken", "RefreshToken")
+}
+
+// TLSCertificate is an option to redact PrivateKey of crypto/tls.Certificate. Certificate chain and other fields are kept.
+func TLSCertificate() masq.Option {
+	return Type("crypto/tls.Certificate", "PrivateKey")
+}
+
+// AWSCredentials is an option to redact SecretAccessKey and SessionToken of AWS credentials, that are aws.Credentials of AWS SDK for Go v2 and credentials.Value of v1. AccessKeyID is kept to identify the credential.
+func AWSCredentials() []masq.Option {
+	return []masq.Option{
+		Type("github.com/aws/aws-sdk-go-v2/aws.Credentials", "SecretAccessKey", "SessionToken"),
+		Type("github.com/aws/aws-sdk-go/aws/credentials.Value", "SecretAccessKey", "SessionToken"),
+	}
+}
+
+// All returns all options in masqpreset.
+func All() []masq.Option {
+	return append([]masq.Option{
+		OAuth2Token(),
+		TLSCertificate(),
+	}, AWSCredentials()...)
+}
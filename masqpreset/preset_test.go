@@ -0,0 +1,114 @@
+package masqpreset_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
+	"github.com/m-mizutani/masq"
+	"github.com/m-mizutani/masq/masqpreset"
+)
+
+// oauth2Token is a stand-in of golang.org/x/oauth2.Token
+type oauth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time
+	ExpiresIn    int64
+	Scopes       []string
+
+	raw any
+}
+
+func TestType(t *testing.T) {
+	expiry := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	token := oauth2Token{
+		AccessToken:  "access-abc",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-xyz",
+		Expiry:       expiry,
+		ExpiresIn:    3600,
+		Scopes:       []string{"email"},
+		raw:          map[string]any{"id_token": "id-123"},
+	}
+	option := masqpreset.Type("github.com/m-mizutani/masq/masqpreset_test.oauth2Token", "AccessToken", "RefreshToken")
+
+	t.Run("redact fields of the type", func(t *testing.T) {
+		copied := gt.Cast[oauth2Token](t, masq.NewMasq(option).Redact(token))
+		gt.V(t, copied.AccessToken).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.TokenType).Equal("Bearer")
+		gt.V(t, copied.RefreshToken).Equal(masq.DefaultRedactMessage)
+		gt.V(t, copied.Expiry).Equal(expiry)
+		gt.V(t, copied.ExpiresIn).Equal(3600)
+		gt.V(t, copied.Scopes).Equal([]string{"email"})
+		gt.V(t, copied.raw).Nil()
+		gt.V(t, token.AccessToken).Equal("access-abc")
+	})
+
+	t.Run("kept fields are deeply copied", func(t *testing.T) {
+		copied := gt.Cast[oauth2Token](t, masq.NewMasq(option).Redact(token))
+		copied.Scopes[0] = "admin"
+		gt.V(t, token.Scopes[0]).Equal("email")
+	})
+
+	t.Run("kept fields are checked by other options", func(t *testing.T) {
+		copied := gt.Cast[oauth2Token](t, masq.NewMasq(option, masq.WithContain("Bearer")).Redact(token))
+		gt.V(t, copied.TokenType).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("custom redact message", func(t *testing.T) {
+		copied := gt.Cast[oauth2Token](t, masq.NewMasq(option, masq.WithRedactMessage("***")).Redact(token))
+		gt.V(t, copied.AccessToken).Equal("***")
+	})
+
+	t.Run("pointer to the type in log", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.New(option),
+		}))
+		logger.Info("hello", slog.Any("token", &token))
+		gt.S(t, buf.String()).
+			Contains(`"AccessToken":"[REDACTED]"`).
+			Contains(`"TokenType":"Bearer"`).
+			NotContains("access-abc").
+			NotContains("refresh-xyz")
+	})
+
+	t.Run("other type is not redacted", func(t *testing.T) {
+		type otherToken oauth2Token
+		copied := gt.Cast[otherToken](t, masq.NewMasq(option).Redact(otherToken{AccessToken: "access-abc"}))
+		gt.V(t, copied.AccessToken).Equal("access-abc")
+	})
+}
+
+func TestTLSCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	gt.NoError(t, err).Must()
+	cert := tls.Certificate{
+		Certificate: [][]byte{[]byte("cert")},
+		PrivateKey:  key,
+	}
+
+	copied := gt.Cast[tls.Certificate](t, masq.NewMasq(masqpreset.TLSCertificate()).Redact(cert))
+	gt.V(t, copied.PrivateKey).Nil()
+	gt.V(t, copied.Certificate).Equal([][]byte{[]byte("cert")})
+	gt.V(t, cert.PrivateKey).NotNil()
+
+	// certificate chain is not shared with the original
+	copied.Certificate[0][0] = 'x'
+	gt.V(t, cert.Certificate[0]).Equal([]byte("cert"))
+}
+
+func TestAll(t *testing.T) {
+	cert := tls.Certificate{PrivateKey: "key"}
+	copied := gt.Cast[tls.Certificate](t, masq.NewMasq(masqpreset.All()...).Redact(cert))
+	gt.V(t, copied.PrivateKey).Equal(any(masq.DefaultRedactMessage))
+	gt.A(t, masqpreset.All()).Length(4)
+}
@@ -2,11 +2,17 @@ package masq
 
 import (
 	"fmt"
+	"net"
 	"reflect"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unsafe"
 )
 
-// Redactor is a function to redact value. It receives source and destination value. If the redaction is done, it must return true. If the redaction is not done, it must return false. If the redaction is not done, the next redactor will be applied. If all redactors are not done, the default redactor will be applied.
+// Redactor is a function to redact value. It receives source and destination value. If the redaction is done, it must return true. If the redaction is not done, it must return false. If the redaction is not done, the next redactor will be applied. If all redactors are not done, the default redactor will be applied. A redactor given to an option is also called with a value of an internal type when the option is applied, and it should return false for the value.
 type Redactor func(src, dst reflect.Value) bool
 
 type Redactors []Redactor
@@ -20,18 +26,231 @@ func (x Redactors) Redact(src, dst reflect.Value) bool {
 	return false
 }
 
-// RedactString is a redactor to redact string value. It receives a function to redact string. The function receives the string value and returns the redacted string value. The returned Redact function always returns true if the source value is string. Otherwise, it returns false.
+// RedactContext is a set of values given to RedactorV2. Src and Dst are same as arguments of Redactor. FieldName and Tag are the field name and the struct tag value of the redacted value, that are same as arguments of Censor. TagOptions are comma-separated options that follow the tag value, e.g. ["keep4"] of `masq:"secret,keep4"`, and they are available only with WithTagOptions option.
+type RedactContext struct {
+	Src        reflect.Value
+	Dst        reflect.Value
+	FieldName  string
+	Tag        string
+	TagOptions []string
+}
+
+// RedactorV2 is a function to redact value in the same way as Redactor. Additionally, it can access the field name and the struct tag value through RedactContext, e.g. to read a parameter of redaction from the tag such as `masq:"mask=4"`. Use Redactor method to give it to options.
+type RedactorV2 func(ctx RedactContext) bool
+
+// Redactor converts RedactorV2 into Redactor to give it to options that accept redactors, e.g. WithTag. FieldName and Tag of RedactContext are empty if the returned Redactor is called outside of masq.
+func (x RedactorV2) Redactor() Redactor {
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		return x(RedactContext{
+			Src:        src,
+			Dst:        call.dst,
+			FieldName:  call.info.fieldName,
+			Tag:        call.info.tag,
+			TagOptions: call.info.tagOptions,
+		})
+	})
+}
+
+// redactInfo is a field name, a tag and a path of the value that is being redacted. tagOptions and path are available only if an option requires them.
+type redactInfo struct {
+	fieldName  string
+	tag        string
+	tagOptions []string
+	path       string
+}
+
+// redactCall is a state of one redaction by redactors. It's allocated by masq for each redaction, and it has Masq that redacts the value, dst given to redactors, the information of the redacted value and the value that replaces the source value. masq is nil if the redactor is called outside of masq.
+type redactCall struct {
+	masq        *Masq
+	dst         reflect.Value
+	info        redactInfo
+	replacement reflect.Value
+}
+
+func newRedactCall(m *Masq, t reflect.Type, info redactInfo) *redactCall {
+	return &redactCall{masq: m, dst: reflect.New(t), info: info}
+}
+
+// redactString returns the redact message of Masq that redacts the value to replace s. DefaultRedactMessage is returned if the redactor is called outside of masq.
+func (x *redactCall) redactString(s string) string {
+	if x.masq == nil {
+		return DefaultRedactMessage
+	}
+	return x.masq.redactString(s)
+}
+
+// replaceWith sets v as the redacted value instead of the value written into dst. It's used by redactors that output a value of different type from the source value.
+func (x *redactCall) replaceWith(v reflect.Value) {
+	x.replacement = v
+}
+
+// callRedactor is a redactor of masq that needs redactCall, e.g. to read the field name or to replace the value with a value of different type.
+type callRedactor struct {
+	fn func(call *redactCall, src reflect.Value) bool
+}
+
+// newCallRedactor returns a redactor that calls fn with redactCall. Options find the callRedactor of the returned redactor by redactProbe, and masq calls fn directly with redactCall.
+func newCallRedactor(fn func(call *redactCall, src reflect.Value) bool) Redactor {
+	return (&callRedactor{fn: fn}).redact
+}
+
+// redact calls fn with a new redactCall that has dst if the redactor is called with src and dst allocated by others, e.g. a custom redactor calls a redactor of masq with its own dst. Then the replacement is set into dst only if it can be assigned. Otherwise, it returns false to leave the value to the next redactor.
+func (x *callRedactor) redact(src, dst reflect.Value) bool {
+	if src.Type() == redactProbeType {
+		dst.Interface().(*redactProbe).redactor = x
+		return src.Interface().(redactProbe).done
+	}
+
+	call := &redactCall{dst: dst}
+	if !x.fn(call, src) {
+		return false
+	}
+	if !call.replacement.IsValid() {
+		return true
+	}
+	if !call.replacement.Type().AssignableTo(dst.Elem().Type()) {
+		return false
+	}
+	dst.Elem().Set(call.replacement)
+	return true
+}
+
+// redactProbe is a value given to a redactor when it's added by an option, to find callRedactor of the redactor. It's a valid pair of src and dst for any redactor, and only callRedactor sets itself into dst. callRedactor returns done of src.
+type redactProbe struct {
+	done     bool
+	redactor *callRedactor
+}
+
+var redactProbeType = reflect.TypeOf(redactProbe{})
+
+// probeCallRedactor returns callRedactor of redactor if redactor is created by newCallRedactor or only passes src and dst to it. Otherwise, it returns nil. redactor is probed twice, with callRedactor that returns false and true, to not regard a custom redactor that calls multiple redactors of masq as one of them. A panic of a custom redactor that does not expect redactProbe is ignored.
+func probeCallRedactor(redactor Redactor) (found *callRedactor) {
+	defer func() {
+		if recover() != nil {
+			found = nil
+		}
+	}()
+
+	probe := func(done bool) (*callRedactor, bool) {
+		dst := reflect.New(redactProbeType)
+		ok := redactor(reflect.ValueOf(redactProbe{done: done}), dst)
+		return dst.Interface().(*redactProbe).redactor, ok
+	}
+	last, ok := probe(false)
+	if last == nil || ok {
+		return nil
+	}
+	if first, ok := probe(true); first != last || !ok {
+		return nil
+	}
+	return last
+}
+
+// filterRedactor is a redactor added by an option with its callRedactor if it has.
+type filterRedactor struct {
+	redactor Redactor
+	call     *callRedactor
+}
+
+// filterRedactors is a list of redactors of a filter. It's built by newFilterRedactors when the option is applied.
+type filterRedactors []filterRedactor
+
+func newFilterRedactors(redactors []Redactor) filterRedactors {
+	if len(redactors) == 0 {
+		return nil
+	}
+	resolved := make(filterRedactors, len(redactors))
+	for i, redactor := range redactors {
+		resolved[i] = filterRedactor{redactor: redactor, call: probeCallRedactor(redactor)}
+	}
+	return resolved
+}
+
+// redact applies the redactors to src in the same way as Redactors.Redact. Redactors created by newCallRedactor receive call, and others receive dst of call.
+func (x filterRedactors) redact(call *redactCall, src reflect.Value) bool {
+	for _, r := range x {
+		if r.call != nil {
+			if r.call.fn(call, src) {
+				return true
+			}
+			continue
+		}
+		if r.redactor(src, call.dst) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropped is a sentinel type to remove the value from output by Drop redactor.
+type dropped struct{}
+
+var droppedType = reflect.TypeOf(dropped{})
+
+// Drop is a redactor to remove the value from output entirely. If the value is a top-level attribute of slog, New and NewHandler remove the attribute from the record. If the value is in a map, the entry is removed from the map. Otherwise, the value is replaced with zero value. Redact returns nil for the dropped value. The returned Redact function always returns true.
+func Drop() Redactor {
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		call.replaceWith(reflect.ValueOf(dropped{}))
+		return true
+	})
+}
+
+// undrop returns zero value of t if v is dropped by Drop redactor. Otherwise, it returns v as is.
+func undrop(v reflect.Value, t reflect.Type) reflect.Value {
+	if v.Type() == droppedType {
+		return reflect.Zero(t)
+	}
+	return v
+}
+
+// sliceHead is a value set by RedactSliceHead redactor. masq clones elems and appends a marker of omitted count.
+type sliceHead struct {
+	elems   reflect.Value
+	omitted int
+}
+
+var sliceHeadType = reflect.TypeOf(sliceHead{})
+
+// RedactSliceHead is a redactor to keep only the first n elements of slice to limit log size while showing a sample. If elements are omitted, the slice is converted to []any and a marker string such as "<97 more>" is appended. Kept elements are redacted by other options in the same way as elements of other slices. The returned Redact function always returns true if the source value is slice. Otherwise, it returns false. If n is negative, RedactSliceHead panics.
+func RedactSliceHead(n int) Redactor {
+	if n < 0 {
+		panic("masq: n of RedactSliceHead must not be negative")
+	}
+
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		if src.Kind() != reflect.Slice {
+			return false
+		}
+
+		kept := min(n, src.Len())
+		call.replaceWith(reflect.ValueOf(sliceHead{
+			elems:   src.Slice(0, kept),
+			omitted: src.Len() - kept,
+		}))
+		return true
+	})
+}
+
+// RedactString is a redactor to redact string value. It receives a function to redact string. The function receives the string value and returns the redacted string value. The destination obtained via unexported field is also set. The returned Redact function always returns true if the source value is string. Otherwise, it returns false.
 func RedactString(redact func(s string) string) Redactor {
 	return func(src, dst reflect.Value) bool {
 		if src.Kind() != reflect.String {
 			return false
 		}
 
-		dst.Elem().SetString(redact(src.String()))
+		settable(dst.Elem()).SetString(redact(src.String()))
 		return true
 	}
 }
 
+// settable returns v that can be set even if v is obtained via unexported field, e.g. dst given by Redactor for an unexported field. It uses unsafe pointer in the same way as cloning unexported fields. If v is not addressable, v is returned as is.
+func settable(v reflect.Value) reflect.Value {
+	if v.CanSet() || !v.CanAddr() {
+		return v
+	}
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+}
+
 // MaskWithSymbol is a redactor to redact string value with masked string that have the same length as the source string value. It can help the developer to know the length of the string value. The returned Redact function always returns true if the source value is string. Otherwise, it returns false.
 func MaskWithSymbol(symbol rune, max int) Redactor {
 	return RedactString(func(s string) string {
@@ -41,3 +260,155 @@ func MaskWithSymbol(symbol rune, max int) Redactor {
 		return strings.Repeat(string(symbol), len(s))
 	})
 }
+
+// RedactConst is a redactor to replace string value with the fixed message. It can be used to set a different redact message for each filter without changing the global redact message by WithRedactMessage. The returned Redact function always returns true if the source value is string. Otherwise, it returns false.
+func RedactConst(message string) Redactor {
+	return RedactString(func(s string) string {
+		return message
+	})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// RedactDate is a redactor to replace time.Time with the date part formatted as "2006-01-02" string to drop time of day for privacy, e.g. birth date and time of a visit. The date is in the location of the time value. The container of the value is converted into a new type that can have the string, e.g. struct field of time.Time is converted into string field. The returned Redact function returns true if the source value is time.Time or non-nil *time.Time. Otherwise, it returns false.
+func RedactDate() Redactor {
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		if src.Kind() == reflect.Ptr && !src.IsNil() && src.Type().Elem() == timeType {
+			src = src.Elem()
+		}
+		if src.Type() != timeType {
+			return false
+		}
+
+		call.replaceWith(reflect.ValueOf(src.Interface().(time.Time).Format(time.DateOnly)))
+		return true
+	})
+}
+
+// RedactBucketInt is a redactor to replace integer with the range of buckets that the value falls in as string, to log approximate counts and amounts without the exact value. buckets are boundaries of the ranges, e.g. []int64{0, 10, 100, 1000}. A value v in b1 <= v < b2 of adjacent boundaries is replaced with "b1-b2", e.g. 150 becomes "100-1000". A value smaller than the first boundary is replaced with "<b", and a value equal to or larger than the last boundary is replaced with ">=b", e.g. "<0" and ">=1000". buckets are sorted and copied, and RedactBucketInt panics if buckets is empty. The container of the value is converted into a new type that can have the string. The returned Redact function returns true if the source value is signed or unsigned integer. Otherwise, it returns false.
+func RedactBucketInt(buckets []int64) Redactor {
+	if len(buckets) == 0 {
+		panic("masq: buckets of RedactBucketInt must not be empty")
+	}
+	bounds := slices.Clone(buckets)
+	slices.Sort(bounds)
+	bounds = slices.Compact(bounds)
+
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		var i int // number of boundaries that are equal to or smaller than the value
+		switch src.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v := src.Int()
+			i = sort.Search(len(bounds), func(i int) bool { return bounds[i] > v })
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			v := src.Uint()
+			i = sort.Search(len(bounds), func(i int) bool { return bounds[i] >= 0 && uint64(bounds[i]) > v })
+		default:
+			return false
+		}
+
+		var bucket string
+		switch i {
+		case 0:
+			bucket = "<" + strconv.FormatInt(bounds[0], 10)
+		case len(bounds):
+			bucket = ">=" + strconv.FormatInt(bounds[i-1], 10)
+		default:
+			bucket = strconv.FormatInt(bounds[i-1], 10) + "-" + strconv.FormatInt(bounds[i], 10)
+		}
+		call.replaceWith(reflect.ValueOf(bucket))
+		return true
+	})
+}
+
+// redactWith is a redactor to replace the value with the value returned by transform.
+func redactWith(transform func(value any) any) Redactor {
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		call.transform(src, transform)
+		return true
+	})
+}
+
+// transform sets the value returned by transform into dst. If the returned value can not be assigned to the source type, it's set by replaceWith.
+func (x *redactCall) transform(src reflect.Value, transform func(value any) any) {
+	v := reflect.ValueOf(transform(src.Interface()))
+	switch {
+	case !v.IsValid():
+		// nil is returned, then keep zero value in dst
+	case v.Type().AssignableTo(x.dst.Elem().Type()):
+		x.dst.Elem().Set(v)
+	default:
+		x.replaceWith(v)
+	}
+}
+
+// RedactStructAs is a redactor to replace struct with the value returned by fn, e.g. map[string]string{"_redacted": t.String()}, to keep a trace of the removed struct in the output. fn receives the struct type, that is the element type if the source value is a pointer to struct. If the returned value is not the struct type, the container of the struct is converted into a new type that can have the value, e.g. map[string]any. If fn returns nil, the struct is replaced with zero value. The returned Redact function returns true if the source value is struct or non-nil pointer to struct. Otherwise, it returns false.
+func RedactStructAs(fn func(t reflect.Type) any) Redactor {
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		t := src.Type()
+		if t.Kind() == reflect.Ptr && !src.IsNil() {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+
+		call.transform(src, func(value any) any {
+			return fn(t)
+		})
+		return true
+	})
+}
+
+// redactIPHost is a redactor to zero host bits of net.IP and net.IPNet. maskBits is applied to 32 bits for IPv4 and 128 bits for IPv6 address.
+func redactIPHost(maskBits int) Redactor {
+	mask := func(ip net.IP) net.IP {
+		if v4 := ip.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(min(maskBits, 32), 32))
+		}
+		return ip.Mask(net.CIDRMask(min(maskBits, 128), 128))
+	}
+
+	return func(src, dst reflect.Value) bool {
+		switch v := src.Interface().(type) {
+		case net.IP:
+			dst.Elem().Set(reflect.ValueOf(mask(v)))
+			return true
+		case net.IPNet:
+			dst.Elem().Set(reflect.ValueOf(net.IPNet{
+				IP:   mask(v.IP),
+				Mask: append(net.IPMask{}, v.Mask...),
+			}))
+			return true
+		}
+		return false
+	}
+}
+
+// maskCardNumber is a redactor to mask digits of credit card number except the last 4 digits. Separators are kept.
+func maskCardNumber() Redactor {
+	return RedactString(func(s string) string {
+		masked := []rune(s)
+		var n int
+		for i := len(masked) - 1; i >= 0; i-- {
+			if masked[i] < '0' || '9' < masked[i] {
+				continue
+			}
+			if n++; n > 4 {
+				masked[i] = '*'
+			}
+		}
+		return string(masked)
+	})
+}
+
+// redactPEMPrivateKeys is a redactor to replace PEM blocks of private key in string with the redact message of Masq that redacts the value. Text around the blocks is kept.
+func redactPEMPrivateKeys() Redactor {
+	return newCallRedactor(func(call *redactCall, src reflect.Value) bool {
+		if src.Kind() != reflect.String {
+			return false
+		}
+		settable(call.dst.Elem()).SetString(pemPrivateKeyBlock.ReplaceAllStringFunc(src.String(), call.redactString))
+		return true
+	})
+}
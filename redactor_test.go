@@ -1,6 +1,15 @@
 package masq_test
 
 import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/m-mizutani/gt"
 	"github.com/m-mizutani/masq"
 )
 
@@ -28,3 +37,446 @@ func ExampleMaskWithSymbol() {
 	// Output:
 	// {"level":"INFO","msg":"Got record","record":{"Email":"************ (remained 36 chars)","ID":"m-mizutani","Phone":"*************"},"time":"2022-12-25T09:00:00.123456789"}
 }
+
+func ExampleRedactConst() {
+	out := &fixedTimeWriter{}
+
+	type myRecord struct {
+		ID       string
+		Password string
+		Token    string
+		Email    string
+	}
+	record := myRecord{
+		ID:       "m-mizutani",
+		Password: "abcd1234",
+		Token:    "xyz9876",
+		Email:    "mizutani@hey.com",
+	}
+
+	logger := newLogger(out, masq.New(
+		masq.WithFieldName("Password", masq.RedactConst("***")),
+		masq.WithFieldName("Token", masq.RedactConst("(token)")),
+		// default redact message is not changed
+		masq.WithFieldName("Email"),
+	))
+	logger.With("record", record).Info("Got record")
+	out.Flush()
+	// Output:
+	// {"level":"INFO","msg":"Got record","record":{"Email":"[REDACTED]","ID":"m-mizutani","Password":"***","Token":"(token)"},"time":"2022-12-25T09:00:00.123456789"}
+}
+
+func TestRedactConst(t *testing.T) {
+	type myRecord struct {
+		Token string
+		Count int
+	}
+	c := masq.NewMasq(
+		masq.WithFieldName("Token", masq.RedactConst("<token>")),
+		masq.WithFieldName("Count", masq.RedactConst("<count>")),
+	)
+
+	copied := gt.Cast[myRecord](t, c.Redact(myRecord{Token: "abcd1234", Count: 5}))
+	gt.V(t, copied.Token).Equal("<token>")
+	// non-string value falls back to the default redactor
+	gt.V(t, copied.Count).Equal(0)
+}
+
+func TestDrop(t *testing.T) {
+	t.Run("top-level attribute is removed by New", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, masq.New(masq.WithFieldName("password", masq.Drop())))
+		logger.Info("hello", slog.String("password", "abcd1234"), slog.String("user", "m-mizutani"))
+
+		gt.S(t, buf.String()).
+			NotContains("password").
+			NotContains("abcd1234").
+			Contains(`"user":"m-mizutani"`)
+	})
+
+	t.Run("top-level attribute is removed by NewHandler", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(masq.NewHandler(slog.NewJSONHandler(&buf, nil),
+			masq.WithFieldName("password", masq.Drop()),
+		))
+		logger.With("password", "abcd1234").Info("hello",
+			slog.Group("g", slog.String("password", "abcd1234"), slog.String("user", "m-mizutani")),
+		)
+
+		gt.S(t, buf.String()).
+			NotContains("password").
+			NotContains("abcd1234").
+			Contains(`"g":{"user":"m-mizutani"}`)
+	})
+
+	t.Run("entry in map is removed", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("password", masq.Drop()))
+		copied := gt.Cast[map[string]string](t, c.Redact(map[string]string{
+			"password": "abcd1234",
+			"user":     "m-mizutani",
+		}))
+		gt.M(t, copied).Length(1).NotHaveKey("password").HaveKeyValue("user", "m-mizutani")
+	})
+
+	t.Run("struct field is zeroed", func(t *testing.T) {
+		type myRecord struct {
+			User     string
+			Password string
+		}
+		c := masq.NewMasq(masq.WithFieldName("Password", masq.Drop()))
+		copied := gt.Cast[myRecord](t, c.Redact(myRecord{User: "m-mizutani", Password: "abcd1234"}))
+		gt.V(t, copied).Equal(myRecord{User: "m-mizutani"})
+	})
+
+	t.Run("Redact returns nil for dropped value", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithContain("abcd", masq.Drop()))
+		gt.V(t, c.Redact("abcd1234")).Nil()
+	})
+}
+
+func TestRedactStringUnexportedField(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		phone string
+		count int
+	}
+	record := myRecord{ID: "m-mizutani", phone: "090-0000-1234", count: 5}
+
+	c := masq.NewMasq(
+		masq.WithFieldName("phone", masq.RedactString(func(s string) string {
+			return "****-" + s[len(s)-4:]
+		})),
+		masq.WithFieldName("count"),
+	)
+	expected := myRecord{ID: "m-mizutani", phone: "****-1234"}
+
+	t.Run("pointer to struct", func(t *testing.T) {
+		gt.V(t, *gt.Cast[*myRecord](t, c.Redact(&record))).Equal(expected)
+	})
+
+	t.Run("struct pointer in map", func(t *testing.T) {
+		copied := gt.Cast[map[string]*myRecord](t, c.Redact(map[string]*myRecord{"a": &record}))
+		gt.V(t, *copied["a"]).Equal(expected)
+	})
+
+	t.Run("unexported destination", func(t *testing.T) {
+		var dst myRecord
+		phone := reflect.ValueOf(&dst).Elem().FieldByName("phone").Addr()
+
+		redact := masq.RedactString(func(s string) string { return "****-" + s[len(s)-4:] })
+		gt.B(t, redact(reflect.ValueOf(record.phone), phone)).True()
+		gt.V(t, dst.phone).Equal("****-1234")
+
+		gt.B(t, masq.MaskWithSymbol('*', 4)(reflect.ValueOf("1234"), phone)).True()
+		gt.V(t, dst.phone).Equal("****")
+	})
+
+	gt.V(t, record.phone).Equal("090-0000-1234")
+}
+
+func TestRedactSliceHead(t *testing.T) {
+	type event struct {
+		ID    int
+		Token string
+	}
+	type myRecord struct {
+		Events []event
+		Few    []event
+	}
+
+	events := make([]event, 100)
+	for i := range events {
+		events[i] = event{ID: i, Token: "secret"}
+	}
+	record := myRecord{
+		Events: events,
+		Few:    events[:2],
+	}
+
+	c := masq.NewMasq(
+		masq.WithFieldName("Events", masq.RedactSliceHead(3)),
+		masq.WithFieldName("Few", masq.RedactSliceHead(3)),
+		masq.WithFieldName("Token"),
+	)
+
+	t.Run("slice is capped with omission marker", func(t *testing.T) {
+		v := reflect.ValueOf(c.Redact(record))
+		capped := gt.Cast[[]any](t, v.FieldByName("Events").Interface())
+		gt.V(t, capped).Equal([]any{
+			event{ID: 0, Token: masq.DefaultRedactMessage},
+			event{ID: 1, Token: masq.DefaultRedactMessage},
+			event{ID: 2, Token: masq.DefaultRedactMessage},
+			"<97 more>",
+		})
+		gt.A(t, record.Events).Length(100)
+	})
+
+	t.Run("short slice keeps the type", func(t *testing.T) {
+		v := reflect.ValueOf(c.Redact(record))
+		few := gt.Cast[[]event](t, v.FieldByName("Few").Interface())
+		gt.V(t, few).Equal([]event{
+			{ID: 0, Token: masq.DefaultRedactMessage},
+			{ID: 1, Token: masq.DefaultRedactMessage},
+		})
+	})
+
+	t.Run("in log", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := newLogger(&buf, masq.New(masq.WithFieldName("ids", masq.RedactSliceHead(2))))
+		logger.Info("hello", slog.Any("ids", []int{1, 2, 3, 4, 5}))
+		gt.S(t, buf.String()).Contains(`"ids":[1,2,"<3 more>"]`)
+	})
+
+	t.Run("negative n", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Failed to panic")
+			}
+		}()
+		masq.RedactSliceHead(-1)
+	})
+}
+
+func TestRedactStructAs(t *testing.T) {
+	type Credential struct {
+		User     string
+		Password string
+	}
+	type myRecord struct {
+		ID         string
+		Credential Credential
+		Backup     *Credential
+	}
+	record := myRecord{
+		ID:         "m-mizutani",
+		Credential: Credential{User: "admin", Password: "abcd1234"},
+		Backup:     &Credential{User: "backup", Password: "xyz"},
+	}
+
+	placeholder := func(t reflect.Type) any {
+		return map[string]string{"_redacted": t.String()}
+	}
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: masq.New(
+			masq.WithType[Credential](masq.RedactStructAs(placeholder)),
+			masq.WithType[*Credential](masq.RedactStructAs(placeholder)),
+		),
+	}))
+	logger.Info("hello", slog.Any("record", record))
+
+	gt.S(t, buf.String()).
+		Contains(`"ID":"m-mizutani"`).
+		Contains(`"Credential":{"_redacted":"masq_test.Credential"}`).
+		Contains(`"Backup":{"_redacted":"masq_test.Credential"}`)
+	gt.S(t, buf.String()).NotContains("abcd1234")
+	gt.S(t, buf.String()).NotContains("xyz")
+
+	t.Run("not struct", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("ID", masq.RedactStructAs(placeholder)))
+		copied := gt.Cast[myRecord](t, c.Redact(myRecord{ID: "m-mizutani"}))
+		gt.V(t, copied.ID).Equal(masq.DefaultRedactMessage)
+	})
+}
+
+func TestRedactDate(t *testing.T) {
+	type myRecord struct {
+		ID        string
+		Birthday  time.Time
+		VisitedAt *time.Time
+		CreatedAt time.Time
+	}
+	birthday := time.Date(1990, 4, 1, 13, 45, 30, 0, time.UTC)
+	visitedAt := time.Date(2024, 12, 31, 23, 59, 59, 0, time.FixedZone("JST", 9*60*60))
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: masq.New(
+			masq.WithFieldName("Birthday", masq.RedactDate()),
+			masq.WithFieldName("VisitedAt", masq.RedactDate()),
+		),
+	}))
+	logger.Info("hello", slog.Any("record", myRecord{
+		ID:        "m-mizutani",
+		Birthday:  birthday,
+		VisitedAt: &visitedAt,
+		CreatedAt: createdAt,
+	}))
+
+	gt.S(t, buf.String()).
+		Contains(`"Birthday":"1990-04-01"`).
+		Contains(`"VisitedAt":"2024-12-31"`).
+		Contains(`"CreatedAt":"2024-01-02T03:04:05Z"`)
+	gt.S(t, buf.String()).NotContains("13:45")
+	gt.S(t, buf.String()).NotContains("23:59")
+
+	t.Run("not time", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("ID", masq.RedactDate()))
+		copied := gt.Cast[myRecord](t, c.Redact(myRecord{ID: "m-mizutani"}))
+		gt.V(t, copied.ID).Equal(masq.DefaultRedactMessage)
+	})
+}
+
+func TestRedactBucketInt(t *testing.T) {
+	type myRecord struct {
+		ID     string
+		Amount int
+		Count  uint32
+		Score  int64
+	}
+	buckets := []int64{1000, 0, 10, 100}
+
+	testCases := map[string]struct {
+		value  int
+		expect string
+	}{
+		"negative":       {value: -5, expect: "<0"},
+		"lower boundary": {value: 0, expect: "0-10"},
+		"in range":       {value: 150, expect: "100-1000"},
+		"upper boundary": {value: 100, expect: "100-1000"},
+		"largest":        {value: 1000, expect: ">=1000"},
+		"very large":     {value: 123456789, expect: ">=1000"},
+	}
+
+	c := masq.NewMasq(masq.WithFieldName("Amount", masq.RedactBucketInt(buckets)))
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			v := c.Redact(myRecord{ID: "m-mizutani", Amount: tc.value})
+			gt.V(t, reflect.ValueOf(v).FieldByName("Amount").Interface()).Equal(any(tc.expect))
+			gt.V(t, reflect.ValueOf(v).FieldByName("ID").Interface()).Equal(any("m-mizutani"))
+		})
+	}
+
+	t.Run("unsigned and slog output", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+			ReplaceAttr: masq.New(
+				masq.WithFieldName("Count", masq.RedactBucketInt(buckets)),
+				masq.WithFieldName("Score", masq.RedactBucketInt(buckets)),
+			),
+		}))
+		logger.Info("hello", slog.Any("record", myRecord{Count: 7, Score: -1}))
+		gt.S(t, buf.String()).
+			Contains(`"Count":"0-10"`).
+			Contains(`"Score":"<0"`)
+	})
+
+	t.Run("not integer", func(t *testing.T) {
+		c := masq.NewMasq(masq.WithFieldName("ID", masq.RedactBucketInt(buckets)))
+		copied := gt.Cast[myRecord](t, c.Redact(myRecord{ID: "m-mizutani"}))
+		gt.V(t, copied.ID).Equal(masq.DefaultRedactMessage)
+	})
+
+	t.Run("empty buckets", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Failed to panic")
+			}
+		}()
+		masq.RedactBucketInt(nil)
+	})
+}
+
+func TestRedactorV2(t *testing.T) {
+	type myRecord struct {
+		ID    string
+		Card  string `masq:"mask=4"`
+		Phone string `masq:"mask=2"`
+		Token string `masq:"mask"`
+	}
+
+	var fieldNames []string
+	keepTail := masq.RedactorV2(func(ctx masq.RedactContext) bool {
+		fieldNames = append(fieldNames, ctx.FieldName)
+		n, err := strconv.Atoi(strings.TrimPrefix(ctx.Tag, "mask="))
+		if err != nil || ctx.Src.Kind() != reflect.String || ctx.Src.Len() < n {
+			return false
+		}
+
+		s := ctx.Src.String()
+		ctx.Dst.Elem().SetString(strings.Repeat("*", len(s)-n) + s[len(s)-n:])
+		return true
+	})
+
+	c := masq.NewMasq(masq.WithCensor(func(fieldName string, value any, tag string) bool {
+		return strings.HasPrefix(tag, "mask")
+	}, keepTail.Redactor()))
+
+	copied := gt.Cast[myRecord](t, c.Redact(myRecord{
+		ID:    "m-mizutani",
+		Card:  "4111111111111111",
+		Phone: "09000000012",
+		Token: "xyz",
+	}))
+	gt.V(t, copied).Equal(myRecord{
+		ID:    "m-mizutani",
+		Card:  "************1111",
+		Phone: "*********12",
+		Token: masq.DefaultRedactMessage,
+	})
+	gt.V(t, fieldNames).Equal([]string{"Card", "Phone", "Token"})
+
+	t.Run("called outside of masq", func(t *testing.T) {
+		fieldNames = nil
+		dst := reflect.New(reflect.TypeOf(""))
+		// tag is empty outside of masq
+		gt.V(t, keepTail.Redactor()(reflect.ValueOf("4111111111111111"), dst)).Equal(false)
+		gt.V(t, fieldNames).Equal([]string{""})
+	})
+}
+
+func TestComposedRedactor(t *testing.T) {
+	// custom redactor that calls redactors of masq with its own dst
+	composed := func(src, dst reflect.Value) bool {
+		own := reflect.New(src.Type())
+		if masq.RedactDate()(src, own) || masq.Drop()(src, own) {
+			dst.Elem().Set(own.Elem())
+			return true
+		}
+		return masq.RedactStructAs(func(t reflect.Type) any { return nil })(src, dst)
+	}
+
+	type myRecord struct {
+		Birthday time.Time
+		Note     string
+	}
+	m := masq.NewMasq(
+		masq.WithType[time.Time](composed),
+		masq.WithFieldName("Note", composed),
+	)
+	copied := gt.Cast[myRecord](t, m.Redact(myRecord{Birthday: time.Now(), Note: "secret"}))
+	// replacement of different type can not be set into own dst, then default redactor is used
+	gt.V(t, copied.Birthday).Equal(time.Time{})
+	gt.V(t, copied.Note).Equal(masq.DefaultRedactMessage)
+
+	t.Run("redactor that only forwards to redactor of masq", func(t *testing.T) {
+		date := masq.RedactDate()
+		forward := func(src, dst reflect.Value) bool {
+			return date(src, dst)
+		}
+		birthday := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+		v := masq.NewMasq(masq.WithType[time.Time](forward)).Redact(birthday)
+		gt.V(t, v).Equal(any("2000-01-02"))
+	})
+
+	t.Run("redactor that calls multiple redactors of masq", func(t *testing.T) {
+		composed := func(src, dst reflect.Value) bool {
+			if masq.RedactDate()(src, dst) {
+				return true
+			}
+			return masq.RedactStructAs(func(t reflect.Type) any { return "struct" })(src, dst)
+		}
+		v := masq.NewMasq(masq.WithType[time.Time](composed)).Redact(time.Now())
+		// redactors of masq can not set a string into dst of time.Time, then the default redactor is used
+		gt.V(t, v).Equal(any(time.Time{}))
+	})
+
+	t.Run("assignable replacement is set into dst", func(t *testing.T) {
+		type wrapper struct{ V int }
+		dst := reflect.New(reflect.TypeOf(wrapper{}))
+		ok := masq.RedactStructAs(func(t reflect.Type) any { return wrapper{V: 1} })(reflect.ValueOf(wrapper{V: 2}), dst)
+		gt.B(t, ok).True()
+		gt.V(t, dst.Elem().Interface()).Equal(any(wrapper{V: 1}))
+	})
+}
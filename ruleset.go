@@ -0,0 +1,78 @@
+package masq
+
+// RuleSet is a set of filters built from options once, to be shared by multiple Masq instances without running the options again. It's useful when Masq is created for each request with the same rules, e.g. per-request policies of NewHandlerWithContext. A RuleSet is immutable and safe to be used by multiple goroutines.
+type RuleSet struct {
+	rules *Masq
+}
+
+// NewRuleSet builds rules from options such as WithRegex and WithFieldName. Filters and rules to select or skip values, that are WithAttrKey, WithMapKeyValueRedaction, WithTopLevelRedactor, WithCallerPackageRules, WithSkipFieldNames and allow options such as WithAllowedType, are kept in RuleSet. Other settings in options such as WithRedactMessage are ignored, then give them to each Masq instead.
+func NewRuleSet(options ...Option) *RuleSet {
+	return &RuleSet{rules: NewMasq(options...)}
+}
+
+// WithRuleSet is an option to add filters of RuleSet. The filters are shared with other Masq instances that have the same RuleSet, and they are checked in the order of options in the same way as filters given directly. Filter names for SetEnabled are the same as the original options, and SetEnabled affects only the Masq that calls it.
+func WithRuleSet(rs *RuleSet) Option {
+	return func(m *Masq) {
+		m.filters = append(m.filters, rs.rules.filters...)
+		for key, filter := range rs.rules.attrKeyFilters {
+			if m.attrKeyFilters == nil {
+				m.attrKeyFilters = map[string]*Filter{}
+			}
+			m.attrKeyFilters[key] = filter
+		}
+		m.mapKeyCensors = append(m.mapKeyCensors, rs.rules.mapKeyCensors...)
+		m.topLevelRedactors = append(m.topLevelRedactors, rs.rules.topLevelRedactors...)
+		if len(rs.rules.callerRules) > 0 {
+			WithCallerPackageRules(rs.rules.callerRules)(m)
+		}
+		m.skipFieldNames = mergeSet(m.skipFieldNames, rs.rules.skipFieldNames)
+		m.allowedTypes = mergeSet(m.allowedTypes, rs.rules.allowedTypes)
+		m.allowedTypeNames = mergeSet(m.allowedTypeNames, rs.rules.allowedTypeNames)
+		m.allowedValues = mergeSet(m.allowedValues, rs.rules.allowedValues)
+		m.allowTags = mergeSet(m.allowTags, rs.rules.allowTags)
+		m.inheritFilterState(rs.rules)
+	}
+}
+
+// mergeSet adds keys of src into dst and returns dst. dst is allocated if it's nil and src is not empty.
+func mergeSet[K comparable](dst, src map[K]struct{}) map[K]struct{} {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[K]struct{}, len(src))
+	}
+	for k := range src {
+		dst[k] = struct{}{}
+	}
+	return dst
+}
+
+// unscopedRules returns names of options in m that can not be scoped by WithAttrScope.
+func unscopedRules(m *Masq) []string {
+	var names []string
+	if len(m.attrKeyFilters) > 0 {
+		names = append(names, "WithAttrKey")
+	}
+	if len(m.mapKeyCensors) > 0 {
+		names = append(names, "WithMapKeyValueRedaction")
+	}
+	if len(m.topLevelRedactors) > 0 {
+		names = append(names, "WithTopLevelRedactor")
+	}
+	if len(m.callerRules) > 0 {
+		names = append(names, "WithCallerPackageRules")
+	}
+	if len(m.skipFieldNames) > 0 {
+		names = append(names, "WithSkipFieldNames")
+	}
+	return names
+}
+
+// inheritFilterState enables the state of redaction that is required by filters of src, e.g. path of values for WithPathMatch, to use the filters in x.
+func (x *Masq) inheritFilterState(src *Masq) {
+	x.pathRequired = x.pathRequired || src.pathRequired
+	x.structFieldRequired = x.structFieldRequired || src.structFieldRequired
+	x.parentRequired = x.parentRequired || src.parentRequired
+	x.attrScoped = x.attrScoped || src.attrScoped
+}
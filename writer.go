@@ -0,0 +1,152 @@
+package masq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxLineLength is the maximum length of a line kept in the buffer of Writer. The buffered data is redacted and written without newline when it exceeds the length.
+const maxLineLength = 64 * 1024
+
+// Writer is an io.Writer that redacts text written into it line by line and writes the redacted text into the inner writer. It's useful to wrap a legacy logger that writes plain text, such as log.Logger of the standard library.
+type Writer struct {
+	inner io.Writer
+	masq  *Masq
+	// rest has filters of masq except ones that find substrings, and they are applied to the whole line.
+	rest *Masq
+
+	mutex sync.Mutex
+	buf   []byte
+}
+
+// NewWriter returns Writer that redacts each line written into it with options and writes the line into w. Only substrings matched by WithContain, WithRegex and WithRegexAny are replaced with the redact message or redactors of the options, then other filters are applied to the whole line without the trailing newline as a string value. Data without trailing newline is buffered until the newline is written or Flush is called, then a secret split into multiple Write calls is also redacted. If the buffered data exceeds 64 KiB, it's redacted and written without newline, then a secret across the boundary is not redacted.
+func NewWriter(w io.Writer, options ...Option) *Writer {
+	rest := NewMasq(options...)
+	rest.filters = slices.DeleteFunc(rest.filters, func(filter *Filter) bool {
+		return filter.find != nil
+	})
+
+	return &Writer{
+		inner: w,
+		masq:  NewMasq(options...),
+		rest:  rest,
+	}
+}
+
+// Write redacts complete lines in the buffered data and p, and writes them into the inner writer. It returns len(p) and nil error if all complete lines are written, even if a part of p is kept in the buffer. If the inner writer fails, it returns the number of bytes of p consumed by the written lines and the error, and the failed line is written again by retrying Write with the rest of p. In strict mode enabled by WithStrict, it returns an error wrapping ErrNotRedacted and discards the line without writing it if the line is not redacted, and the discarded line is counted as consumed.
+func (x *Writer) Write(p []byte) (int, error) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	var n int
+	for {
+		idx := bytes.IndexByte(p[n:], '\n')
+		if idx < 0 {
+			break
+		}
+		line := append(slices.Clip(x.buf), p[n:n+idx]...)
+		out, err := x.redactLine(line, true)
+		if err != nil {
+			x.buf = nil
+			return n + idx + 1, err
+		}
+		if _, err := io.WriteString(x.inner, out); err != nil {
+			// keep the buffer to write the line again by retry
+			return n, err
+		}
+		x.buf = nil
+		n += idx + 1
+	}
+
+	x.buf = append(x.buf, p[n:]...)
+	if len(x.buf) > maxLineLength {
+		if err := x.flush(); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush redacts the buffered data that does not end with newline and writes it into the inner writer. Call it before closing the inner writer. If the inner writer fails, the data is kept in the buffer.
+func (x *Writer) Flush() error {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+
+	return x.flush()
+}
+
+// flush writes the buffered data in the same way as Flush. The caller must hold the mutex.
+func (x *Writer) flush() error {
+	if len(x.buf) == 0 {
+		return nil
+	}
+	out, err := x.redactLine(x.buf, false)
+	if err != nil {
+		x.buf = nil
+		return err
+	}
+	if _, err := io.WriteString(x.inner, out); err != nil {
+		return err
+	}
+	x.buf = nil
+	return nil
+}
+
+// redactLine returns the redacted line to be written into the inner writer.
+func (x *Writer) redactLine(line []byte, newline bool) (string, error) {
+	replaced, err := x.redactMatches(string(line))
+	if err != nil {
+		return "", err
+	}
+
+	redacted, err := x.rest.RedactE(replaced)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprint(redacted)
+	if newline {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// redactMatches replaces substrings of line matched by enabled filters that find substrings with the redacted substrings.
+func (x *Writer) redactMatches(line string) (string, error) {
+	var found [][]int
+	for _, filter := range x.masq.filters {
+		if filter.find != nil && x.masq.isEnabled(filter) {
+			found = append(found, filter.find(line)...)
+		}
+	}
+	if len(found) == 0 {
+		return line, nil
+	}
+
+	slices.SortFunc(found, func(a, b []int) int { return a[0] - b[0] })
+	var b strings.Builder
+	var last int
+	for i := 0; i < len(found); {
+		start, end := found[i][0], found[i][1]
+		// merge overlapped substrings
+		for i++; i < len(found) && found[i][0] < end; i++ {
+			end = max(end, found[i][1])
+		}
+
+		redacted, err := x.masq.RedactE(line[start:end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(line[last:start])
+		if redacted != nil {
+			b.WriteString(fmt.Sprint(redacted))
+		}
+		last = end
+	}
+	b.WriteString(line[last:])
+	return b.String(), nil
+}
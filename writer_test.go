@@ -0,0 +1,176 @@
+package masq_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/m-mizutani/gt"
+	"github.com/m-mizutani/masq"
+)
+
+func TestWriter(t *testing.T) {
+	t.Run("line is redacted", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf, masq.WithContain("abcd1234"))
+		_, err := w.Write([]byte("login user=m-mizutani\npassword=abcd1234\n"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("login user=m-mizutani\npassword=[REDACTED]\n")
+	})
+
+	t.Run("secret split into multiple writes", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf, masq.WithContain("abcd1234"))
+		for _, chunk := range []string{"token=ab", "cd", "1234 user=m-mizutani", "\nhello\n"} {
+			n, err := w.Write([]byte(chunk))
+			gt.NoError(t, err)
+			gt.V(t, n).Equal(len(chunk))
+		}
+		gt.V(t, buf.String()).Equal("token=[REDACTED] user=m-mizutani\nhello\n")
+	})
+
+	t.Run("partial line is written by Flush", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf, masq.WithContain("abcd1234"))
+		_, err := w.Write([]byte("hello\ntoken=abcd"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("hello\n")
+
+		_, err = w.Write([]byte("1234"))
+		gt.NoError(t, err)
+		gt.NoError(t, w.Flush())
+		gt.V(t, buf.String()).Equal("hello\ntoken=[REDACTED]")
+		gt.NoError(t, w.Flush())
+		gt.V(t, buf.String()).Equal("hello\ntoken=[REDACTED]")
+	})
+
+	t.Run("replace only secret part of line", func(t *testing.T) {
+		re := regexp.MustCompile(`token=\S+`)
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf, masq.WithRegex(re, masq.RedactString(func(s string) string {
+			return re.ReplaceAllString(s, "token=****")
+		})))
+		_, err := w.Write([]byte("login token=abcd1234 user=m-mizutani\n"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("login token=**** user=m-mizutani\n")
+	})
+
+	t.Run("replace only matched substrings by regex", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf,
+			masq.WithRegex(regexp.MustCompile(`\d{3}-\d{4}-\d{4}`)),
+			masq.WithContain("abcd1234"),
+		)
+		_, err := w.Write([]byte("call 090-0000-0000 or 080-1111-1111 with abcd1234\n"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("call [REDACTED] or [REDACTED] with [REDACTED]\n")
+	})
+
+	t.Run("other filters are applied to whole line", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf,
+			masq.WithContain("abcd1234"),
+			masq.WithType[string](),
+		)
+		_, err := w.Write([]byte("password=abcd1234\n"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("[REDACTED]\n")
+	})
+
+	t.Run("long line is flushed without newline", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf, masq.WithContain("abcd1234"))
+		long := "password=abcd1234 " + strings.Repeat("x", 64*1024)
+		n, err := w.Write([]byte(long))
+		gt.NoError(t, err)
+		gt.V(t, n).Equal(len(long))
+		gt.V(t, buf.String()).Equal("password=[REDACTED] " + strings.Repeat("x", 64*1024))
+
+		_, err = w.Write([]byte("\nhello\n"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("password=[REDACTED] " + strings.Repeat("x", 64*1024) + "\nhello\n")
+	})
+
+	t.Run("wrap log.Logger", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := log.New(masq.NewWriter(&buf, masq.WithContain("abcd1234")), "", 0)
+		logger.Printf("password=%s", "abcd1234")
+		logger.Printf("user=%s", "m-mizutani")
+		gt.V(t, buf.String()).Equal("password=[REDACTED]\nuser=m-mizutani\n")
+	})
+
+	t.Run("error in strict mode", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := masq.NewWriter(&buf,
+			masq.WithContain("abcd1234", masq.RedactString(func(s string) string { return s })),
+			masq.WithStrict(),
+		)
+		_, err := w.Write([]byte("password=abcd1234\n"))
+		gt.B(t, errors.Is(err, masq.ErrNotRedacted)).True()
+		gt.V(t, buf.String()).Equal("")
+
+		// the line is discarded
+		_, err = w.Write([]byte("hello\n"))
+		gt.NoError(t, err)
+		gt.V(t, buf.String()).Equal("hello\n")
+	})
+
+	t.Run("retry after error of inner writer", func(t *testing.T) {
+		inner := &flakyWriter{failAt: 2}
+		w := masq.NewWriter(inner, masq.WithContain("abcd1234"))
+		p := []byte("hello\npassword=abcd1234\nbye\n")
+
+		n, err := w.Write(p)
+		gt.V(t, err).Equal(errFlaky)
+		gt.V(t, n).Equal(len("hello\n"))
+		gt.V(t, inner.buf.String()).Equal("hello\n")
+
+		n, err = w.Write(p[n:])
+		gt.NoError(t, err)
+		gt.V(t, n).Equal(len("password=abcd1234\nbye\n"))
+		gt.V(t, inner.buf.String()).Equal("hello\npassword=[REDACTED]\nbye\n")
+	})
+
+	t.Run("buffered data is kept after error of inner writer", func(t *testing.T) {
+		inner := &flakyWriter{failAt: 1}
+		w := masq.NewWriter(inner)
+		_, err := w.Write([]byte("hel"))
+		gt.NoError(t, err)
+
+		n, err := w.Write([]byte("lo\n"))
+		gt.V(t, err).Equal(errFlaky)
+		gt.V(t, n).Equal(0)
+
+		n, err = w.Write([]byte("lo\n"))
+		gt.NoError(t, err)
+		gt.V(t, n).Equal(3)
+		gt.V(t, inner.buf.String()).Equal("hello\n")
+
+		_, err = w.Write([]byte("world"))
+		gt.NoError(t, err)
+		inner.failAt = inner.calls + 1
+		gt.V(t, w.Flush()).Equal(errFlaky)
+		gt.NoError(t, w.Flush())
+		gt.V(t, inner.buf.String()).Equal("hello\nworld")
+	})
+}
+
+var errFlaky = errors.New("flaky")
+
+// flakyWriter fails only at the failAt-th call of Write.
+type flakyWriter struct {
+	buf    bytes.Buffer
+	calls  int
+	failAt int
+}
+
+func (x *flakyWriter) Write(p []byte) (int, error) {
+	x.calls++
+	if x.calls == x.failAt {
+		return 0, errFlaky
+	}
+	return x.buf.Write(p)
+}